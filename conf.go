@@ -1,43 +1,237 @@
 package main
 
 import (
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/dlepex/newsmaker/news"
 )
 
 type config struct {
-	RTick     duration            `toml:"rotation_tick"`
-	MuteHours *[2]int             `toml:"mute_hours"`
-	Filters   []*filterConf       `toml:"filters"`
-	Sources   map[string]*srcConf `toml:"src"`
-	Pubs      map[string]*pubConf `toml:"pub"`
+	RTick      duration            `toml:"rotation_tick"`
+	RMode      string              `toml:"rotation_mode"`        // "random" (default) or "all"
+	RConc      int                 `toml:"rotation_concurrency"` // "all" mode: max simultaneous polls
+	RJitter    duration            `toml:"rotation_jitter"`      // "all" mode: random delay of each poll
+	RFair      bool                `toml:"rotation_fair"`        // "all" mode: least recently polled go first
+	MuteHours  *[2]int             `toml:"mute_hours"`
+	Mute       []string            `toml:"mute"`        // mute windows (see news.ParseSchedule), added to mute_hours
+	Timezone   string              `toml:"timezone"`    // IANA time zone of mute/schedule checks, local by default
+	MaxAge     duration            `toml:"max_age"`     // default for sources
+	NoDate     string              `toml:"no_date"`     // default for sources
+	FutureDate string              `toml:"future_date"` // default for sources
+	Filters    []*filterConf       `toml:"filters"`
+	Sources    map[string]*srcConf `toml:"src"`
+	Pubs       map[string]*pubConf `toml:"pub"`
+	WebSub     *webSubConf         `toml:"websub"`
+	Admin      *adminConf          `toml:"admin"`
+	DeadLetter string              `toml:"dead_letter"` // optional file, items that pubs failed to send are stored there
+
+	websub *news.WebSub // created by newPipeline, if configured
+	dl     *news.DeadLetter
+}
+
+type webSubConf struct {
+	CallbackURL string   `toml:"callback_url"`
+	Listen      string   `toml:"listen"`
+	Lease       duration `toml:"lease"`
+}
+
+type adminConf struct {
+	Listen string `toml:"listen"`
+	Token  string `toml:"token"`
+}
+
+func (c *adminConf) params() news.AdminParams {
+	return news.AdminParams{Addr: c.Listen, Token: c.Token}
+}
+
+// pubEnv - global settings shared by all publishers
+type pubEnv struct {
+	loc      *time.Location
+	dl       *news.DeadLetter
+	limiters map[string]*sharedLimiter // by rate_share name or telegram bot token
+}
+
+type sharedLimiter struct {
+	*news.RateLimiter
+	rate  string
+	burst int
+}
+
+// srcEnv - global settings shared by all sources
+type srcEnv struct {
+	mute       news.Schedule
+	loc        *time.Location
+	websub     *news.WebSub
+	maxAge     time.Duration
+	noDate     string
+	futureDate string
 }
 
 type filterConf struct {
+	Name    string   `toml:"name"` // optional, digest grouping
 	Cond    string   `toml:"cond"`
 	Sources []string `toml:"sources"`
 	Pubs    []string `toml:"pubs"`
 }
 
 type srcConf struct {
-	CD    duration `toml:"cd"`
-	Links []string `toml:"links"`
+	Type string   `toml:"type"` // "rss" (default, rss/atom feed), "html", "ingest", "mail", "file" or "exec"
+	CD   duration `toml:"cd"`
+	// CDMin, CDMax - adaptive cooldown bounds, cooldown is adjusted by the rate of new items and feed hints
+	CDMin duration `toml:"cd_min"`
+	CDMax duration `toml:"cd_max"`
+	Links []string `toml:"links"` // feed urls or site urls (feed is discovered by <link rel="alternate">)
 	Categ []string `toml:"categ"`
+	// Mute - source own mute windows, e.g. ["Sat,Sun", "Mon-Fri 23:00-07:00"], global mute by default
+	Mute []string `toml:"mute"`
+	// Cron - optional polling schedule (5-field cron expression), overrides cooldown
+	Cron string `toml:"cron"`
+	// Weight - relative chance to be picked by random rotation, 1 by default
+	Weight int `toml:"weight"`
+	// Priority - the source is polled before the others, once its cooldown has passed
+	Priority bool `toml:"priority"`
+	// Timezone - time zone of mute and cron, global timezone by default
+	Timezone string `toml:"timezone"`
+	// MaxAge - items older than that are dropped, global max_age by default
+	MaxAge duration `toml:"max_age"`
+	// NoDate - "keep", "drop" or "now" (set date to receive time) items without date
+	NoDate string `toml:"no_date"`
+	// FutureDate - "keep", "drop" or "now" items dated in the future
+	FutureDate string `toml:"future_date"`
+	// OPML - subscriptions file, its feeds are added to Links
+	OPML string `toml:"opml"`
+	// OPMLSplit - each OPML category becomes a separate source: "<name>.<category>"
+	OPMLSplit bool `toml:"opml_split"`
+
+	// html source css selectors
+	ItemSel    string `toml:"item_sel"`
+	TitleSel   string `toml:"title_sel"`
+	LinkSel    string `toml:"link_sel"`
+	DateSel    string `toml:"date_sel"`
+	DateLayout string `toml:"date_layout"`
+
+	// ingest (push) source params
+	Listen string `toml:"listen"`
+	Path   string `toml:"path"`
+	Token  string `toml:"token"`
+
+	// mail source params: either imap or maildir
+	MailMode     string `toml:"mail_mode"`
+	IMAP         string `toml:"imap"`
+	IMAPUser     string `toml:"imap_user"`
+	IMAPPassword string `toml:"imap_password"`
+	IMAPMailbox  string `toml:"imap_mailbox"`
+	IMAPStartTLS bool   `toml:"imap_starttls"`
+	Maildir      string `toml:"maildir"`
+
+	// file source params
+	Dir     string `toml:"dir"`
+	Pattern string `toml:"pattern"`
+
+	// exec source params
+	Command []string `toml:"command"`
+	Timeout duration `toml:"timeout"`
 }
 
 type pubConf struct {
+	Type      string   `toml:"type"` // "http" (default, get_url), "telegram", "webhook" or "email"
 	SendPause duration `toml:"send_pause"`
 	GetURL    string   `toml:"get_url"`
 	Template  string   `toml:"template"` // optional go template (Item struct fields)
+	Timezone  string   `toml:"timezone"` // dates formatting time zone, global timezone by default
+
+	// quiet hours: sources keep polling, but matched items are queued till the end of the window
+	Quiet          []string `toml:"quiet"`
+	QuietDigest    bool     `toml:"quiet_digest"`    // deliver queued items as one message
+	QuietQueue     string   `toml:"quiet_queue"`     // optional file, the queue is persisted there
+	DigestTemplate string   `toml:"digest_template"` // optional go template (news.DigestData)
+
+	// digest mode: items are buffered and sent as one message per interval or cron period
+	Mode           string   `toml:"mode"` // "" (each item is sent) or "digest"
+	DigestInterval duration `toml:"digest_interval"`
+	DigestCron     string   `toml:"digest_cron"`
+	DigestMax      int      `toml:"digest_max"`   // digest is sent early when so many items are buffered
+	DigestQueue    string   `toml:"digest_queue"` // optional file, the buffer is persisted there
+
+	// retry policy: max attempts, exponential backoff, retryable http statuses (news.RetryableDefault by default)
+	Retry           int      `toml:"retry"`
+	RetryBackoff    duration `toml:"retry_backoff"`
+	RetryMaxBackoff duration `toml:"retry_max_backoff"`
+	RetryStatus     []int    `toml:"retry_status"`
+
+	// Overflow - full queue policy: "drop-newest" (default), "drop-oldest", "block" or "spill" (to spill_file)
+	Overflow  string `toml:"overflow"`
+	SpillFile string `toml:"spill_file"`
+
+	// rate limit (token bucket): "N/period", e.g. "20/1m", up to rate_burst items are sent at once (1 by default),
+	// pubs with the same rate_share share the limiter (they must have the same rate)
+	Rate      string `toml:"rate"`
+	RateBurst int    `toml:"rate_burst"`
+	RateShare string `toml:"rate_share"`
+
+	// long messages: max_length (runes, 4096 for telegram) and length_policy: "truncate" (default) or "split",
+	// markup entities aren't broken: telegram parse_mode, markup of http pub (parse_mode of get_url by default)
+	MaxLength    int    `toml:"max_length"`
+	LengthPolicy string `toml:"length_policy"`
+	Markup       string `toml:"markup"`
+	MaxURL       int    `toml:"max_url"` // http pub url limit, news.HTTPPubMaxURL by default
+
+	// telegram params
+	Token          string `toml:"token"`
+	ChatID         chatID `toml:"chat_id"`
+	ParseMode      string `toml:"parse_mode"` // "MarkdownV2", "HTML", "Markdown" or "" (plain text)
+	ThreadID       int64  `toml:"thread_id"`
+	DisablePreview bool   `toml:"disable_preview"`
+	Silent         bool   `toml:"silent"`
+	Photo          bool   `toml:"photo"`
+	APIURL         string `toml:"api_url"`
+
+	// webhook params
+	URL          string            `toml:"url"`
+	Method       string            `toml:"method"`
+	Headers      map[string]string `toml:"headers"`
+	Body         string            `toml:"body"` // go template (news.WebhookData, email: news.EmailData)
+	ContentType  string            `toml:"content_type"`
+	ExpectStatus []int             `toml:"expect_status"`
+	ExpectBody   string            `toml:"expect_body"`
+
+	// email params
+	SMTP         string   `toml:"smtp"` // host:port
+	SMTPSecurity string   `toml:"smtp_security"`
+	SMTPUser     string   `toml:"smtp_user"`
+	SMTPPassword string   `toml:"smtp_password"`
+	From         string   `toml:"from"`
+	To           []string `toml:"to"`
+	Subject      string   `toml:"subject"`
+	HTML         string   `toml:"html"`
+}
+
+// chatID - telegram chat id, either number or "@channel"
+type chatID string
+
+func (c *chatID) UnmarshalTOML(v interface{}) error {
+	switch v := v.(type) {
+	case string:
+		*c = chatID(v)
+	case int64:
+		*c = chatID(strconv.FormatInt(v, 10))
+	default:
+		return fmt.Errorf("chat_id: number or string expected")
+	}
+	return nil
 }
 
 func (c *config) newPipeline() (pl *news.Pipeline, ers []error) {
 	pl = news.NewPipelineDefault()
-	globalMuteHours := news.DayInterval{}
-	if c.MuteHours != nil {
-		globalMuteHours = news.DayHoursFromTo(c.MuteHours[0], c.MuteHours[1])
-	}
+	env := srcEnv{maxAge: c.MaxAge.Duration, noDate: c.NoDate, futureDate: c.FutureDate}
 	check := func(e error) bool {
 		if e == nil {
 			return true
@@ -45,16 +239,50 @@ func (c *config) newPipeline() (pl *news.Pipeline, ers []error) {
 		ers = append(ers, e)
 		return false
 	}
+	mute := c.Mute
+	if c.MuteHours != nil {
+		mute = append([]string{fmt.Sprintf("%d:00-%d:00", c.MuteHours[0], c.MuteHours[1])}, mute...)
+	}
+	check(pl.SetRotation(news.RotationParams{
+		Tick:        c.RTick.Duration,
+		Mode:        news.RotationMode(c.RMode),
+		Concurrency: c.RConc,
+		Jitter:      c.RJitter.Duration,
+		Fair:        c.RFair,
+	}))
+	var err error
+	env.mute, err = news.ParseSchedule(mute...)
+	check(err)
+	env.loc, err = loadLocation(c.Timezone)
+	check(err)
+	if c.WebSub != nil {
+		ws, err := news.NewWebSub(news.WebSubParams{
+			CallbackURL: c.WebSub.CallbackURL,
+			Addr:        c.WebSub.Listen,
+			Lease:       c.WebSub.Lease.Duration,
+		})
+		if check(err) {
+			c.websub = ws
+			env.websub = ws
+		}
+	}
+	penv := c.pubEnv(env.loc)
 	for n, c := range c.Pubs {
-		pub, err := c.toPub(n)
+		pub, err := c.toPub(n, penv)
 		if check(err) {
 			check(pl.AddPublisher(pub))
 		}
 	}
 	for n, c := range c.Sources {
-		src, err := c.toSource(n, globalMuteHours)
-		if check(err) {
-			check(pl.AddSource(src))
+		confs, err := c.expand(n)
+		if !check(err) {
+			continue
+		}
+		for n, c := range confs {
+			src, err := c.toSource(n, &env)
+			if check(err) {
+				check(pl.AddSource(src))
+			}
 		}
 	}
 	for _, c := range c.Filters {
@@ -68,28 +296,340 @@ func (c *config) newPipeline() (pl *news.Pipeline, ers []error) {
 }
 
 func (c *filterConf) toFilter() *news.Filter {
-	return &news.Filter{Cond: c.Cond, Sources: c.Sources, Pubs: c.Pubs}
+	return &news.Filter{Name: c.Name, Cond: c.Cond, Sources: c.Sources, Pubs: c.Pubs}
+}
+
+// expand - reads opml file (if any) and returns source confs by name
+func (c *srcConf) expand(n string) (map[string]*srcConf, error) {
+	if c.OPML == "" {
+		return map[string]*srcConf{n: c}, nil
+	}
+	f, err := os.Open(c.OPML)
+	if err != nil {
+		return nil, fmt.Errorf("src %s: %s", n, err)
+	}
+	defer f.Close() // nolint:errcheck
+	o, err := news.ReadOPML(f)
+	if err != nil {
+		return nil, fmt.Errorf("src %s: opml: %s", n, err)
+	}
+	feeds := o.Feeds()
+	m := make(map[string]*srcConf)
+	if !c.OPMLSplit {
+		cc := *c
+		cc.Links = append([]string{}, c.Links...)
+		for _, links := range feeds {
+			cc.Links = append(cc.Links, links...)
+		}
+		m[n] = &cc
+		return m, nil
+	}
+	if len(c.Links) != 0 {
+		feeds[""] = append(append([]string{}, c.Links...), feeds[""]...)
+	}
+	for categ, links := range feeds {
+		cc := *c
+		cc.Links = links
+		name := n
+		if categ != "" {
+			name = n + "." + strings.Replace(categ, " ", "_", -1)
+		}
+		m[name] = &cc
+	}
+	return m, nil
 }
 
-func (c *srcConf) toSource(n string, muteHours news.DayInterval) (news.Source, error) {
-	return news.NewFeedSrc(news.FeedSrcParams{
-		SourceInfo: news.SourceInfo{
-			Name:         n,
-			Categories:   c.Categ,
-			Cooldown:     c.CD.Duration,
-			MuteInterval: muteHours,
+func (c *srcConf) toSource(n string, env *srcEnv) (news.Source, error) {
+	info := news.SourceInfo{
+		Name:         n,
+		Categories:   c.Categ,
+		Cooldown:     c.CD.Duration,
+		CooldownMin:  c.CDMin.Duration,
+		CooldownMax:  c.CDMax.Duration,
+		MuteInterval: env.mute,
+		MaxAge:       c.MaxAge.Duration,
+		NoDate:       news.DatePolicy(c.NoDate),
+		FutureDate:   news.DatePolicy(c.FutureDate),
+		Weight:       c.Weight,
+		Priority:     c.Priority,
+	}
+	if c.Mute != nil {
+		mute, err := news.ParseSchedule(c.Mute...)
+		if err != nil {
+			return nil, fmt.Errorf("src %s: %s", n, err)
+		}
+		info.MuteInterval = mute
+	}
+	info.Location = env.loc
+	if c.Timezone != "" {
+		loc, err := loadLocation(c.Timezone)
+		if err != nil {
+			return nil, fmt.Errorf("src %s: %s", n, err)
+		}
+		info.Location = loc
+	}
+	if c.Cron != "" {
+		cron, err := news.ParseCron(c.Cron)
+		if err != nil {
+			return nil, fmt.Errorf("src %s: %s", n, err)
+		}
+		info.Cron = cron
+	}
+	if info.MaxAge == 0 {
+		info.MaxAge = env.maxAge
+	}
+	if info.NoDate == "" {
+		info.NoDate = news.DatePolicy(env.noDate)
+	}
+	if info.FutureDate == "" {
+		info.FutureDate = news.DatePolicy(env.futureDate)
+	}
+	switch c.Type {
+	case "", "rss":
+		return news.NewFeedSrc(news.FeedSrcParams{
+			SourceInfo: info,
+			Links:      c.Links,
+			WebSub:     env.websub,
+		})
+	case "html":
+		return news.NewHTMLSrc(news.HTMLSrcParams{
+			SourceInfo: info,
+			Links:      c.Links,
+			ItemSel:    c.ItemSel,
+			TitleSel:   c.TitleSel,
+			LinkSel:    c.LinkSel,
+			DateSel:    c.DateSel,
+			DateLayout: c.DateLayout,
+		})
+	case "ingest":
+		return news.NewIngestSrc(news.IngestSrcParams{
+			SourceInfo: info,
+			Addr:       c.Listen,
+			Path:       c.Path,
+			Token:      c.Token,
+		})
+	case "mail":
+		var box news.Mailbox
+		if c.Maildir != "" {
+			box = news.Maildir(c.Maildir)
+		} else if c.IMAP != "" {
+			box = &news.IMAPBox{
+				Addr:     c.IMAP,
+				User:     c.IMAPUser,
+				Password: c.IMAPPassword,
+				Mailbox:  c.IMAPMailbox,
+				StartTLS: c.IMAPStartTLS,
+			}
+		}
+		return news.NewMailSrc(news.MailSrcParams{
+			SourceInfo: info,
+			Mailbox:    box,
+			Mode:       news.MailSrcMode(c.MailMode),
+		})
+	case "file":
+		return news.NewFileSrc(news.FileSrcParams{
+			SourceInfo: info,
+			Dir:        c.Dir,
+			Pattern:    c.Pattern,
+		})
+	case "exec":
+		return news.NewExecSrc(news.ExecSrcParams{
+			SourceInfo: info,
+			Command:    c.Command,
+			Dir:        c.Dir,
+			Timeout:    c.Timeout.Duration,
+		})
+	default:
+		return nil, fmt.Errorf("src %s: unknown type: %s", n, c.Type)
+	}
+}
+
+func (c *config) pubEnv(loc *time.Location) *pubEnv {
+	return &pubEnv{loc: loc, dl: c.deadLetter(), limiters: make(map[string]*sharedLimiter)}
+}
+
+// limiter - new rate limiter or the shared one, if key isn't empty
+func (e *pubEnv) limiter(key, rate string, burst int) (*news.RateLimiter, error) {
+	if l, ok := e.limiters[key]; ok {
+		if l.rate != rate || l.burst != burst {
+			return nil, fmt.Errorf("%s: shared limiter rate mismatch: %s burst %d", key, l.rate, l.burst)
+		}
+		return l.RateLimiter, nil
+	}
+	n, per, err := news.ParseRate(rate)
+	if err != nil {
+		return nil, err
+	}
+	l := news.NewRateLimiter(n, per, burst)
+	if key != "" {
+		e.limiters[key] = &sharedLimiter{l, rate, burst}
+	}
+	return l, nil
+}
+
+// limiters - rate/rate_share limiter and bot token limiter of telegram pubs
+func (c *pubConf) limiters(env *pubEnv) ([]*news.RateLimiter, error) {
+	var res []*news.RateLimiter
+	if c.Rate != "" {
+		key := ""
+		if c.RateShare != "" {
+			key = "rate_share " + c.RateShare
+		}
+		l, err := env.limiter(key, c.Rate, c.RateBurst)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, l)
+	} else if c.RateShare != "" {
+		return nil, errors.New("rate_share requires rate")
+	}
+	if c.Type == "telegram" && c.Token != "" {
+		// bot api limit: about 30 messages per second (all chats)
+		l, err := env.limiter("telegram bot "+strings.SplitN(c.Token, ":", 2)[0], "30/s", 30)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, l)
+	}
+	return res, nil
+}
+
+// markup - message markup, telegram parse_mode of get_url by default
+// (unknown parse_mode means plain text, the message is cut regardless of markup)
+func (c *pubConf) markup() string {
+	if c.Markup != "" || c.Type != "" && c.Type != "http" {
+		return c.Markup
+	}
+	if u, err := url.Parse(c.GetURL); err == nil {
+		if m, ok := news.NormalizeMarkup(u.Query().Get("parse_mode")); ok {
+			return m
+		}
+	}
+	return news.TgPlain
+}
+
+// pubInfo - publisher name, time zone, retry policy, dead letter store and rate limiters
+func (c *pubConf) pubInfo(n string, env *pubEnv) (news.PubInfo, error) {
+	loc := env.loc
+	if c.Timezone != "" {
+		var err error
+		if loc, err = loadLocation(c.Timezone); err != nil {
+			return news.PubInfo{}, fmt.Errorf("pub %s: %s", n, err)
+		}
+	}
+	limiters, err := c.limiters(env)
+	if err != nil {
+		return news.PubInfo{}, fmt.Errorf("pub %s: %s", n, err)
+	}
+	return news.PubInfo{
+		Name:     n,
+		Location: loc,
+		Retry: news.RetryPolicy{
+			MaxAttempts: c.Retry,
+			Backoff:     c.RetryBackoff.Duration,
+			MaxBackoff:  c.RetryMaxBackoff.Duration,
+			Retryable:   c.RetryStatus,
 		},
-		Links: c.Links,
-	})
+		DeadLetter: env.dl,
+		Overflow:   news.OverflowPolicy(c.Overflow),
+		SpillFile:  c.SpillFile,
+		Limiters:   limiters,
+		MaxLength:  c.MaxLength,
+		Length:     news.LengthPolicy(c.LengthPolicy),
+		Markup:     c.markup(),
+	}, nil
 }
 
-func (c *pubConf) toPub(n string) (news.Pub, error) {
+func (c *pubConf) toPub(n string, env *pubEnv) (news.Pub, error) {
+	info, err := c.pubInfo(n, env)
+	if err != nil {
+		return nil, err
+	}
+	pub, err := c.newPub(info)
+	if err != nil {
+		return nil, err
+	}
+	pub, err = c.wrap(pub, info.Location)
+	if err != nil {
+		return nil, fmt.Errorf("pub %s: %s", n, err)
+	}
+	return pub, nil
+}
+
+func (c *pubConf) newPub(info news.PubInfo) (news.Pub, error) {
+	switch c.Type {
+	case "", "http":
+	case "telegram":
+		params := news.TelegramPubParams{
+			PubInfo:        info,
+			Token:          c.Token,
+			ChatID:         string(c.ChatID),
+			ParseMode:      c.ParseMode,
+			ThreadID:       c.ThreadID,
+			DisablePreview: c.DisablePreview,
+			Silent:         c.Silent,
+			Photo:          c.Photo,
+			Pause:          c.SendPause.Duration,
+			API:            c.APIURL,
+		}
+		if c.Template != "" {
+			params.ItemStringer = news.NewItemTemplateStringer(c.Template)
+		}
+		pub, err := news.NewTelegramPub(params)
+		if err != nil {
+			return nil, fmt.Errorf("pub %s: %s", info.Name, err)
+		}
+		return pub, nil
+	case "webhook":
+		params := news.WebhookPubParams{
+			PubInfo:     info,
+			Method:      c.Method,
+			URL:         c.URL,
+			Headers:     c.Headers,
+			Body:        c.Body,
+			ContentType: c.ContentType,
+			Expect:      c.ExpectStatus,
+			ExpectBody:  c.ExpectBody,
+			Pause:       c.SendPause.Duration,
+		}
+		if c.Template != "" {
+			params.ItemStringer = news.NewItemTemplateStringer(c.Template)
+		}
+		pub, err := news.NewWebhookPub(params)
+		if err != nil {
+			return nil, fmt.Errorf("pub %s: %s", info.Name, err)
+		}
+		return pub, nil
+	case "email":
+		params := news.EmailPubParams{
+			PubInfo:  info,
+			Addr:     c.SMTP,
+			Security: c.SMTPSecurity,
+			Username: c.SMTPUser,
+			Password: c.SMTPPassword,
+			From:     c.From,
+			To:       c.To,
+			Subject:  c.Subject,
+			Body:     c.Body,
+			HTML:     c.HTML,
+			Pause:    c.SendPause.Duration,
+		}
+		if c.Template != "" {
+			params.ItemStringer = news.NewItemTemplateStringer(c.Template)
+		}
+		pub, err := news.NewEmailPub(params)
+		if err != nil {
+			return nil, fmt.Errorf("pub %s: %s", info.Name, err)
+		}
+		return pub, nil
+	default:
+		return nil, fmt.Errorf("pub %s: unknown type: %s", info.Name, c.Type)
+	}
 	params := &news.HTTPPubParams{
-		PubInfo: news.PubInfo{
-			Name: n,
-		},
-		Link:  c.GetURL,
-		Pause: c.SendPause.Duration,
+		PubInfo: info,
+		Link:    c.GetURL,
+		Pause:   c.SendPause.Duration,
+		MaxURL:  c.MaxURL,
 	}
 	tpl := c.Template
 	if tpl == "" {
@@ -99,6 +639,197 @@ func (c *pubConf) toPub(n string) (news.Pub, error) {
 	return news.NewHTTPPub(params), nil
 }
 
+// wrap - applies publisher wrappers: quiet hours, then digest (digests are held within quiet hours)
+func (c *pubConf) wrap(pub news.Pub, loc *time.Location) (news.Pub, error) {
+	if c.QuietDigest && c.Mode == "digest" {
+		// quiet queue would hold the digests and make the digest of them
+		return nil, errors.New(`quiet_digest can't be used with mode = "digest"`)
+	}
+	if len(c.Quiet) != 0 {
+		quiet, err := news.ParseSchedule(c.Quiet...)
+		if err != nil {
+			return nil, err
+		}
+		if loc != nil {
+			quiet = quiet.In(loc)
+		}
+		params := news.QuietPubParams{
+			Quiet:     quiet,
+			Digest:    c.QuietDigest,
+			QueueFile: c.QuietQueue,
+		}
+		if c.DigestTemplate != "" {
+			params.DigestStringer = news.NewDigestTemplateStringer(c.DigestTemplate, pub.Info())
+		}
+		if pub, err = news.NewQuietPub(pub, params); err != nil {
+			return nil, err
+		}
+	}
+	switch c.Mode {
+	case "":
+	case "digest":
+		params := news.DigestPubParams{
+			Interval:  c.DigestInterval.Duration,
+			MaxItems:  c.DigestMax,
+			QueueFile: c.DigestQueue,
+		}
+		if c.DigestCron != "" {
+			cron, err := news.ParseCron(c.DigestCron)
+			if err != nil {
+				return nil, err
+			}
+			if loc != nil {
+				cron = cron.In(loc)
+			}
+			params.Cron = cron
+		}
+		if c.DigestTemplate != "" {
+			params.DigestStringer = news.NewDigestTemplateStringer(c.DigestTemplate, pub.Info())
+		}
+		var err error
+		if pub, err = news.NewDigestPub(pub, params); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unknown mode: %s", c.Mode)
+	}
+	return pub, nil
+}
+
+// deadLetter - dead letter store, nil if not configured
+func (c *config) deadLetter() *news.DeadLetter {
+	if c.dl == nil && c.DeadLetter != "" {
+		c.dl = news.NewDeadLetter(c.DeadLetter)
+	}
+	return c.dl
+}
+
+// listDeadLetter writes dead letter items: failure time, pub, error, title and link
+func (c *config) listDeadLetter(w io.Writer) error {
+	dl := c.deadLetter()
+	if dl == nil {
+		return errors.New("dead_letter isn't configured")
+	}
+	items, err := dl.List()
+	if err != nil {
+		return err
+	}
+	for _, di := range items {
+		if _, err := fmt.Fprintf(w, "%s\t%s\t%s\t%s %s\n", di.Failed.Format(time.RFC3339), di.Pub, di.Error, di.Title, di.Link); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// resend - publishes dead letter items again (only to the given pubs, if any),
+// items that fail again return to the store. Returns the number of resent items.
+func (c *config) resend(only []string) (int, error) {
+	dl := c.deadLetter()
+	if dl == nil {
+		return 0, errors.New("dead_letter isn't configured")
+	}
+	loc, err := loadLocation(c.Timezone)
+	if err != nil {
+		return 0, err
+	}
+	items, err := dl.Take()
+	if err != nil {
+		return 0, err
+	}
+	byPub := make(map[string][]news.DeadItem)
+	for _, di := range items {
+		_, ok := c.Pubs[di.Pub]
+		if ok && (len(only) == 0 || contains(only, di.Pub)) {
+			byPub[di.Pub] = append(byPub[di.Pub], di)
+		} else if err := dl.Put(di); err != nil {
+			return 0, err
+		}
+	}
+	n := 0
+	var pubErr error
+	env := c.pubEnv(loc)
+	for name, items := range byPub {
+		pc := c.Pubs[name]
+		info, err := pc.pubInfo(name, env)
+		var pub news.Pub
+		if err == nil {
+			pub, err = pc.newPub(info)
+		}
+		if err != nil {
+			for _, di := range items {
+				if err := dl.Put(di); err != nil {
+					return n, err
+				}
+			}
+			pubErr = err
+			continue
+		}
+		ch := make(chan *news.Item, len(items))
+		for _, di := range items {
+			ch <- di.Item
+		}
+		close(ch)
+		pub.Publish(ch)
+		n += len(items)
+	}
+	// taken items are kept till all of them are resent or put back
+	if err := dl.Done(); err != nil {
+		return n, err
+	}
+	return n, pubErr
+}
+
+func contains(xs []string, s string) bool {
+	for _, x := range xs {
+		if x == s {
+			return true
+		}
+	}
+	return false
+}
+
+// exportOPML writes rss sources links as OPML: each source is a category outline
+func (c *config) exportOPML(w io.Writer) error {
+	names := make([]string, 0, len(c.Sources))
+	for n := range c.Sources {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	o := &news.OPML{Title: "newsmaker subscriptions"}
+	for _, n := range names {
+		src := c.Sources[n]
+		if src.Type != "" && src.Type != "rss" {
+			continue
+		}
+		confs, err := src.expand(n)
+		if err != nil {
+			return err
+		}
+		cnames := make([]string, 0, len(confs))
+		for cn := range confs {
+			cnames = append(cnames, cn)
+		}
+		sort.Strings(cnames)
+		for _, cn := range cnames {
+			cat := news.OPMLOutline{Text: cn}
+			for _, l := range confs[cn].Links {
+				cat.Outlines = append(cat.Outlines, news.OPMLOutline{Text: l, Type: "rss", XMLURL: l})
+			}
+			o.Outlines = append(o.Outlines, cat)
+		}
+	}
+	return o.Write(w)
+}
+
+// loadLocation - nil (local time) for empty name
+func loadLocation(name string) (*time.Location, error) {
+	if name == "" {
+		return nil, nil
+	}
+	return time.LoadLocation(name)
+}
+
 type duration struct {
 	time.Duration
 }
@@ -0,0 +1,159 @@
+package news
+
+import (
+	"bytes"
+	"encoding/xml"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/net/html/charset"
+)
+
+// PollHinter - optional Source interface: source knows the minimal polling interval requested by
+// the feed publisher (rss <ttl>, sy:updatePeriod, Cache-Control max-age), 0 if unknown.
+// PollHint is called after Receive.
+type PollHinter interface {
+	PollHint() time.Duration
+}
+
+// AdaptiveTarget - desired number of new items per poll, adaptive cooldown grows if less are received
+// and shrinks if more.
+var AdaptiveTarget = 3
+
+// adaptiveCooldown - effective cooldown of the source in adaptive mode (SourceInfo.CooldownMax > 0)
+type adaptiveCooldown struct {
+	name     string
+	min, max time.Duration
+	cd       int64        // atomic, current cooldown
+	seen     Deduplicator // items already received by the source (Fetch may run along with rotation)
+}
+
+func newAdaptiveCooldown(info *SourceInfo) *adaptiveCooldown {
+	if info.CooldownMax <= 0 {
+		return nil
+	}
+	return &adaptiveCooldown{
+		name: info.Name,
+		min:  info.CooldownMin,
+		max:  info.CooldownMax,
+		cd:   int64(info.Cooldown),
+		seen: DedupSync(NewDedup(1024)),
+	}
+}
+
+func (a *adaptiveCooldown) get() time.Duration {
+	return time.Duration(atomic.LoadInt64(&a.cd))
+}
+
+// countNew wraps sink: counts items that weren't received before
+func (a *adaptiveCooldown) countNew(sink func(*Item), count *int) func(*Item) {
+	return func(it *Item) {
+		k := StrToDedupKey(it.Link, it.Title)
+		if it.GUID != "" {
+			k = it.id
+		}
+		if a.seen.Keep(k) {
+			*count++
+		}
+		sink(it)
+	}
+}
+
+// update adjusts cooldown after the poll: multiplicative increase if nothing new was received,
+// decrease if there were more new items than target. Hint is the lower bound.
+func (a *adaptiveCooldown) update(newItems int, hint time.Duration) time.Duration {
+	cd := a.get()
+	switch {
+	case newItems == 0:
+		cd = cd * 3 / 2
+	case newItems > 2*AdaptiveTarget:
+		cd /= 2
+	case newItems > AdaptiveTarget:
+		cd = cd * 3 / 4
+	}
+	if cd < hint {
+		cd = hint
+	}
+	if cd < a.min {
+		cd = a.min
+	}
+	if cd > a.max {
+		cd = a.max
+	}
+	atomic.StoreInt64(&a.cd, int64(cd))
+	slog.Infow("cooldown_adapt", "src", a.name, "new", newItems, "hint", hint, "cd", cd)
+	return cd
+}
+
+var syPeriods = map[string]time.Duration{
+	"hourly":  time.Hour,
+	"daily":   24 * time.Hour,
+	"weekly":  7 * 24 * time.Hour,
+	"monthly": 30 * 24 * time.Hour,
+	"yearly":  365 * 24 * time.Hour,
+}
+
+// feedPollHint - rss <ttl> (minutes) or sy:updatePeriod / sy:updateFrequency of the feed
+func feedPollHint(data []byte) time.Duration {
+	d := xml.NewDecoder(bytes.NewReader(data))
+	d.Strict = false
+	d.CharsetReader = charset.NewReaderLabel
+	var ttl, period time.Duration
+	freq := 1
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			break
+		}
+		el, ok := tok.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		switch el.Name.Local {
+		case "item", "entry":
+			// channel level elements only
+			return feedHint(ttl, period, freq)
+		case "ttl", "updatePeriod", "updateFrequency":
+			var v string
+			if d.DecodeElement(&v, &el) != nil {
+				continue
+			}
+			v = strings.TrimSpace(v)
+			switch el.Name.Local {
+			case "ttl":
+				if m, err := strconv.Atoi(v); err == nil && m > 0 {
+					ttl = time.Duration(m) * time.Minute
+				}
+			case "updatePeriod":
+				period = syPeriods[v]
+			case "updateFrequency":
+				if f, err := strconv.Atoi(v); err == nil && f > 0 {
+					freq = f
+				}
+			}
+		}
+	}
+	return feedHint(ttl, period, freq)
+}
+
+func feedHint(ttl, period time.Duration, freq int) time.Duration {
+	if ttl > 0 {
+		return ttl
+	}
+	return period / time.Duration(freq)
+}
+
+// cacheControlMaxAge - max-age of Cache-Control header
+func cacheControlMaxAge(h string) time.Duration {
+	for _, d := range strings.Split(h, ",") {
+		d = strings.TrimSpace(d)
+		if strings.HasPrefix(d, "max-age=") {
+			if s, err := strconv.Atoi(d[len("max-age="):]); err == nil && s > 0 {
+				return time.Duration(s) * time.Second
+			}
+		}
+	}
+	return 0
+}
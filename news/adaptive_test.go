@@ -0,0 +1,50 @@
+package news
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAdaptiveCooldown(t *testing.T) {
+	info := &SourceInfo{Name: "a", Cooldown: 10 * time.Minute, CooldownMax: time.Hour}
+	assert.NoError(t, info.Check())
+	assert.Equal(t, 150*time.Second, info.CooldownMin)
+	a := newAdaptiveCooldown(info)
+	assert.Equal(t, 10*time.Minute, a.get())
+
+	assert.Equal(t, 15*time.Minute, a.update(0, 0))
+	assert.Equal(t, 15*time.Minute, a.update(AdaptiveTarget, 0))
+	assert.Equal(t, 7*time.Minute+30*time.Second, a.update(2*AdaptiveTarget+1, 0))
+	assert.Equal(t, 20*time.Minute, a.update(2*AdaptiveTarget+1, 20*time.Minute), "hint is the lower bound")
+	for i := 0; i < 10; i++ {
+		a.update(0, 0)
+	}
+	assert.Equal(t, time.Hour, a.get())
+	for i := 0; i < 10; i++ {
+		a.update(100, 0)
+	}
+	assert.Equal(t, 150*time.Second, a.get())
+
+	n := 0
+	sink := a.countNew(func(*Item) {}, &n)
+	it, _ := NewItem(ItemParams{Title: "x", Link: "l"})
+	sink(it)
+	sink(it)
+	assert.Equal(t, 1, n)
+
+	assert.Nil(t, newAdaptiveCooldown(&SourceInfo{Cooldown: time.Minute}))
+	assert.Error(t, (&SourceInfo{Name: "b", Cooldown: 2 * time.Hour, CooldownMax: time.Hour}).Check())
+}
+
+func TestFeedPollHint(t *testing.T) {
+	assert.Equal(t, 30*time.Minute, feedPollHint([]byte(`<rss><channel><ttl>30</ttl><item><ttl>1</ttl></item></channel></rss>`)))
+	assert.Equal(t, 6*time.Hour, feedPollHint([]byte(`<rss xmlns:sy="http://purl.org/rss/1.0/modules/syndication/"><channel>
+<sy:updatePeriod>daily</sy:updatePeriod><sy:updateFrequency>4</sy:updateFrequency></channel></rss>`)))
+	assert.Equal(t, time.Duration(0), feedPollHint([]byte(`<feed><entry/></feed>`)))
+	assert.Equal(t, time.Hour, feedPollHint([]byte("<?xml version=\"1.0\" encoding=\"windows-1251\"?>\n"+
+		"<rss><channel><title>\xcd\xee\xe2\xee\xf1\xf2\xe8</title><ttl>60</ttl></channel></rss>")))
+	assert.Equal(t, 5*time.Minute, cacheControlMaxAge("public, max-age=300"))
+	assert.Equal(t, time.Duration(0), cacheControlMaxAge("no-cache"))
+}
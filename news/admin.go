@@ -0,0 +1,155 @@
+package news
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/dlepex/newsmaker/strext"
+)
+
+// AdminParams - admin api params
+type AdminParams struct {
+	Addr  string // listen address, e.g. "127.0.0.1:8091"
+	Token string // expected in "Authorization: Bearer <token>" header, optional if Addr is loopback
+}
+
+// Admin - http api of the running pipeline:
+// POST /fetch/<source> - receives the source immediately (see Pipeline.Fetch)
+// GET /stats - {"dropped": {"<pub>": N}}, items dropped due to publisher queue overflow
+type Admin struct {
+	AdminParams
+	pl *Pipeline
+}
+
+type adminResp struct {
+	Src    string `json:"src"`
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// NewAdmin creates admin api of the pipeline
+func NewAdmin(pl *Pipeline, p AdminParams) (*Admin, error) {
+	if strext.IsBlank(p.Addr) {
+		return nil, errors.New("admin: listen address required")
+	}
+	if p.Token == "" && !loopbackAddr(p.Addr) {
+		return nil, fmt.Errorf("admin: token required, unless listen address is loopback: %s", p.Addr)
+	}
+	return &Admin{AdminParams: p, pl: pl}, nil
+}
+
+// loopbackAddr - true if host of the listen address is localhost or loopback ip
+func loopbackAddr(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// Handler - admin api http handler
+func (a *Admin) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/fetch/", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if !a.authorized(r) {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		name := strings.TrimPrefix(r.URL.Path, "/fetch/")
+		resp := adminResp{Src: name, Status: "fetched"}
+		st := http.StatusOK
+		if err := a.pl.Fetch(name); err != nil {
+			resp.Status, resp.Error = "error", err.Error()
+			switch {
+			case errors.Is(err, ErrSourceBusy):
+				st = http.StatusConflict
+			case errors.Is(err, ErrUnknownSource):
+				st = http.StatusNotFound
+			default:
+				st = http.StatusBadRequest
+			}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(st)
+		json.NewEncoder(w).Encode(&resp) // nolint:errcheck
+	})
+	mux.HandleFunc("/stats", func(w http.ResponseWriter, r *http.Request) {
+		if !a.authorized(r) {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{"dropped": a.pl.Dropped()}) // nolint:errcheck
+	})
+	return mux
+}
+
+func (a *Admin) authorized(r *http.Request) bool {
+	if a.Token == "" {
+		return true
+	}
+	return bearerAuthorized(r, a.Token)
+}
+
+// Run - runs admin server until quit is closed
+func (a *Admin) Run(quit <-chan struct{}) error {
+	srv := &http.Server{Addr: a.Addr, Handler: a.Handler()}
+	errc := make(chan error, 1)
+	go func() {
+		errc <- srv.ListenAndServe()
+	}()
+	slog.Infow("admin_listen", "addr", a.Addr)
+	select {
+	case err := <-errc:
+		return err
+	case <-quit:
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		return srv.Shutdown(ctx)
+	}
+}
+
+// Fetch asks the running daemon (admin api at p.Addr) to fetch the source
+func (p AdminParams) Fetch(src string) error {
+	host := p.Addr
+	if strings.HasPrefix(host, ":") {
+		host = "127.0.0.1" + host
+	}
+	req, err := http.NewRequest(http.MethodPost, "http://"+host+"/fetch/"+url.PathEscape(src), nil)
+	if err != nil {
+		return err
+	}
+	if p.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+p.Token)
+	}
+	r, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer r.Body.Close() // nolint:errcheck
+	if st := r.StatusCode; !(200 <= st && st < 300) {
+		var resp adminResp
+		data, _ := io.ReadAll(r.Body)
+		if json.Unmarshal(data, &resp) == nil && resp.Error != "" {
+			return fmt.Errorf("fetch %s: %s", src, resp.Error)
+		}
+		return fmt.Errorf("fetch %s: %s %s", src, r.Status, strings.TrimSpace(string(data)))
+	}
+	return nil
+}
@@ -0,0 +1,94 @@
+package news
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// funcSrc - test source, Receive calls fn
+type funcSrc struct {
+	SourceInfo
+	fn func(sink func(*Item)) error
+}
+
+func (s *funcSrc) Info() *SourceInfo              { return &s.SourceInfo }
+func (s *funcSrc) Receive(sink func(*Item)) error { return s.fn(sink) }
+
+func TestAdminFetch(t *testing.T) {
+	assert := assert.New(t)
+	started, block := make(chan struct{}, 1), make(chan struct{})
+	src := &funcSrc{SourceInfo: SourceInfo{Name: "src", Cooldown: time.Hour}, fn: func(sink func(*Item)) error {
+		started <- struct{}{}
+		<-block
+		sink(testItem("alpha news"))
+		return nil
+	}}
+	assert.NoError(src.Check())
+	pub := newChanPub("pub")
+	pl := NewPipelineDefault()
+	assert.Error(pl.Fetch("src"), "not running")
+	assert.NoError(pl.AddSource(src))
+	assert.NoError(pl.AddPublisher(pub))
+	assert.NoError(pl.AddFilter(&Filter{Cond: "alpha"}))
+	assert.NoError(pl.Run())
+	defer pl.Stop()
+
+	admin, err := NewAdmin(pl, AdminParams{Addr: "unused", Token: "secret"})
+	assert.NoError(err)
+	srv := httptest.NewServer(admin.Handler())
+	defer srv.Close()
+	client := AdminParams{Addr: strings.TrimPrefix(srv.URL, "http://"), Token: "secret"}
+
+	done := make(chan error)
+	go func() { done <- client.Fetch("src") }()
+	<-started
+	err = client.Fetch("src")
+	if assert.Error(err) {
+		assert.Contains(err.Error(), "busy")
+	}
+	close(block)
+	assert.NoError(<-done)
+	select {
+	case it := <-pub.out:
+		assert.Equal("alpha news", it.Title)
+	case <-time.After(time.Second):
+		t.Error("item wasn't published")
+	}
+	// cooldown is reset
+	assert.False(pl.rot.Elems[0].ready(time.Now()))
+
+	err = client.Fetch("nope")
+	if assert.Error(err) {
+		assert.Contains(err.Error(), "unknown source")
+	}
+	req, _ := http.NewRequest("GET", srv.URL+"/stats", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	r, err := http.DefaultClient.Do(req)
+	if assert.NoError(err) {
+		var stats struct{ Dropped map[string]int64 }
+		assert.NoError(json.NewDecoder(r.Body).Decode(&stats))
+		r.Body.Close() // nolint:errcheck
+		assert.Equal(map[string]int64{"pub": 0}, stats.Dropped)
+	}
+
+	_, err = NewAdmin(pl, AdminParams{Addr: ":8091"})
+	assert.Error(err, "no token on all interfaces")
+	_, err = NewAdmin(pl, AdminParams{Addr: "0.0.0.0:8091"})
+	assert.Error(err)
+	_, err = NewAdmin(pl, AdminParams{Addr: "127.0.0.1:8091"})
+	assert.NoError(err)
+	_, err = NewAdmin(pl, AdminParams{Addr: "localhost:8091"})
+	assert.NoError(err)
+
+	client.Token = "wrong"
+	err = client.Fetch("src")
+	if assert.Error(err) {
+		assert.Contains(err.Error(), "401")
+	}
+}
@@ -0,0 +1,68 @@
+package news
+
+import (
+	"fmt"
+	"time"
+)
+
+// DatePolicy - what to do with items that have no date or have date in the future.
+type DatePolicy string
+
+const (
+	// DateKeep - item is kept (default)
+	DateKeep DatePolicy = "keep"
+	// DateDrop - item is dropped
+	DateDrop DatePolicy = "drop"
+	// DateNow - item date is set to the receive time
+	DateNow DatePolicy = "now"
+)
+
+// FutureSkew - items dated in the future within this duration are considered to be current (clock skew).
+var FutureSkew = 10 * time.Minute
+
+func (p DatePolicy) check() error {
+	switch p {
+	case "", DateKeep, DateDrop, DateNow:
+		return nil
+	}
+	return fmt.Errorf("unknown date policy: %s", p)
+}
+
+// itemDate - the latest of published and updated dates
+func itemDate(it *Item) *time.Time {
+	d := it.Published
+	if u := it.Updated; u != nil && (d == nil || u.After(*d)) {
+		d = u
+	}
+	return d
+}
+
+// fresh - applies MaxAge, NoDate and FutureDate policies, it may set item date.
+func (s *SourceInfo) fresh(it *Item, now time.Time) bool {
+	d := itemDate(it)
+	if d == nil {
+		switch s.NoDate {
+		case DateDrop:
+			return false
+		case DateNow:
+			it.Published = &now
+		}
+		return true
+	}
+	if d.Sub(now) > FutureSkew {
+		switch s.FutureDate {
+		case DateDrop:
+			return false
+		case DateNow:
+			// both dates are clamped, itemDate would pick the future one
+			if it.Published == nil || it.Published.Sub(now) > FutureSkew {
+				it.Published = &now
+			}
+			if it.Updated != nil && it.Updated.Sub(now) > FutureSkew {
+				it.Updated = &now
+			}
+		}
+		return true
+	}
+	return s.MaxAge <= 0 || now.Sub(*d) <= s.MaxAge
+}
@@ -0,0 +1,48 @@
+package news
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSourceFresh(t *testing.T) {
+	now := time.Date(2018, 3, 1, 12, 0, 0, 0, time.UTC)
+	at := func(d time.Duration) *time.Time {
+		t := now.Add(d)
+		return &t
+	}
+	item := func(published, updated *time.Time) *Item {
+		return &Item{ItemParams: ItemParams{Title: "t", Published: published, Updated: updated}}
+	}
+
+	s := &SourceInfo{MaxAge: time.Hour}
+	assert.True(t, s.fresh(item(at(-time.Minute), nil), now))
+	assert.False(t, s.fresh(item(at(-2*time.Hour), nil), now))
+	assert.True(t, s.fresh(item(at(-2*time.Hour), at(-time.Minute)), now), "updated date is used if later")
+	assert.True(t, s.fresh(item(nil, nil), now), "keep undated by default")
+	assert.True(t, s.fresh(item(at(time.Minute), nil), now), "within skew")
+
+	s = &SourceInfo{NoDate: DateDrop, FutureDate: DateDrop}
+	assert.True(t, s.fresh(item(at(-100*time.Hour), nil), now), "no max age")
+	assert.False(t, s.fresh(item(nil, nil), now))
+	assert.False(t, s.fresh(item(at(time.Hour), nil), now))
+
+	s = &SourceInfo{NoDate: DateNow, FutureDate: DateNow}
+	it := item(nil, nil)
+	assert.True(t, s.fresh(it, now))
+	assert.Equal(t, now, *it.Published)
+	it = item(at(time.Hour), nil)
+	assert.True(t, s.fresh(it, now))
+	assert.Equal(t, now, *it.Published)
+	// only updated date is in the future
+	it = item(at(-time.Hour), at(time.Hour))
+	assert.True(t, s.fresh(it, now))
+	assert.Equal(t, now, *itemDate(it))
+	assert.Equal(t, now.Add(-time.Hour), *it.Published)
+	s.MaxAge = time.Minute
+	assert.True(t, s.fresh(it, now), "the date is now after clamping")
+
+	assert.Error(t, (&SourceInfo{Name: "x", NoDate: "maybe"}).Check())
+}
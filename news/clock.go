@@ -0,0 +1,38 @@
+package news
+
+import (
+	"math/rand"
+	"time"
+)
+
+// Clock - time source of the scheduling (rotator, mute and quiet windows, item freshness),
+// may be replaced by fake one in tests.
+type Clock interface {
+	Now() time.Time
+	After(d time.Duration) <-chan time.Time
+}
+
+// Rand - random source of the scheduling, *rand.Rand implements it.
+// It's used by the rotator goroutine only, so it doesn't have to be safe for concurrent use.
+type Rand interface {
+	Intn(n int) int
+	Int63n(n int64) int64
+	Shuffle(n int, swap func(i, j int))
+}
+
+// SystemClock - real time clock
+var SystemClock Clock = systemClock{}
+
+// SystemRand - math/rand global source
+var SystemRand Rand = systemRand{}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time                         { return time.Now() }
+func (systemClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+
+type systemRand struct{}
+
+func (systemRand) Intn(n int) int                     { return rand.Intn(n) }
+func (systemRand) Int63n(n int64) int64               { return rand.Int63n(n) }
+func (systemRand) Shuffle(n int, swap func(i, j int)) { rand.Shuffle(n, swap) }
@@ -0,0 +1,181 @@
+package news
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeClock - manually moved clock, After channels fire when the time is moved past their deadline
+type fakeClock struct {
+	mu      sync.Mutex
+	now     time.Time
+	waiters []fakeWaiter
+}
+
+type fakeWaiter struct {
+	at time.Time
+	ch chan time.Time
+}
+
+func newFakeClock(t time.Time) *fakeClock {
+	return &fakeClock{now: t}
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) After(d time.Duration) <-chan time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	ch := make(chan time.Time, 1)
+	if d <= 0 {
+		ch <- c.now
+		return ch
+	}
+	c.waiters = append(c.waiters, fakeWaiter{c.now.Add(d), ch})
+	return ch
+}
+
+// Set moves the clock to t, due After channels fire
+func (c *fakeClock) Set(t time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = t
+	rest := c.waiters[:0]
+	for _, w := range c.waiters {
+		if w.at.After(t) {
+			rest = append(rest, w)
+		} else {
+			w.ch <- t
+		}
+	}
+	c.waiters = rest
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.Set(c.Now().Add(d))
+}
+
+// waitAfter blocks until there are n pending After calls (e.g. rotator is waiting for the next tick)
+func (c *fakeClock) waitAfter(t *testing.T, n int) {
+	for end := time.Now().Add(time.Second); time.Now().Before(end); time.Sleep(time.Millisecond) {
+		c.mu.Lock()
+		l := len(c.waiters)
+		c.mu.Unlock()
+		if l >= n {
+			return
+		}
+	}
+	t.Fatalf("fake clock: %d pending After calls expected", n)
+}
+
+// seqRand - Rand that returns the given numbers (mod n)
+type seqRand struct {
+	seq []int
+}
+
+func (r *seqRand) next() int {
+	v := r.seq[0]
+	r.seq = append(r.seq[1:], v)
+	return v
+}
+
+func (r *seqRand) Intn(n int) int                     { return r.next() % n }
+func (r *seqRand) Int63n(n int64) int64               { return int64(r.next()) % n }
+func (r *seqRand) Shuffle(n int, swap func(i, j int)) {}
+
+func TestRotatorCooldownClock(t *testing.T) {
+	assert := assert.New(t)
+	start := time.Date(2018, 3, 5, 12, 0, 0, 0, time.UTC)
+	clock := newFakeClock(start)
+	calls := make(chan time.Time, 10)
+	rot := &rotator{
+		RotationParams: RotationParams{Tick: time.Minute},
+		Elems:          []rotatorElem{{Cooldown: 3 * time.Minute, Fn: func(now time.Time) { calls <- now }}},
+		clock:          clock,
+	}
+	quit := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		rot.run(quit)
+		close(done)
+	}()
+	for i := 0; i < 7; i++ {
+		clock.waitAfter(t, 1)
+		clock.Advance(time.Minute)
+	}
+	clock.waitAfter(t, 1)
+	close(quit)
+	<-done
+	var got []time.Duration
+	for len(got) < 3 {
+		select {
+		case now := <-calls:
+			got = append(got, now.Sub(start))
+		case <-time.After(time.Second):
+			t.Fatalf("polls: %v", got)
+		}
+	}
+	assert.Equal([]time.Duration{time.Minute, 4 * time.Minute, 7 * time.Minute}, got)
+	assert.Len(calls, 0)
+}
+
+func TestPipelineMuteClock(t *testing.T) {
+	assert := assert.New(t)
+	clock := newFakeClock(time.Date(2018, 3, 5, 21, 30, 0, 0, time.UTC))
+	mute, _ := ParseSchedule("23:00-08:00")
+	polls := make(chan time.Time, 20)
+	src := &funcSrc{SourceInfo: SourceInfo{Name: "src", Cooldown: time.Hour, MuteInterval: mute}, fn: func(sink func(*Item)) error {
+		polls <- clock.Now()
+		return nil
+	}}
+	assert.NoError(src.Check())
+	pl := NewPipelineDefault()
+	assert.NoError(pl.SetClock(clock, &seqRand{seq: []int{0}}))
+	assert.NoError(pl.SetRotation(RotationParams{Tick: time.Hour}))
+	assert.NoError(pl.AddSource(src))
+	assert.NoError(pl.AddPublisher(newChanPub("pub")))
+	assert.NoError(pl.AddFilter(&Filter{Cond: "x"}))
+	assert.NoError(pl.Run())
+	defer pl.Stop()
+
+	// ticks at 22:30 (polled), 23:30..07:30 (muted), 08:30 (polled)
+	for h := 0; h < 11; h++ {
+		clock.waitAfter(t, 1)
+		clock.Advance(time.Hour)
+		if h == 0 || h == 10 {
+			select {
+			case now := <-polls:
+				assert.Equal(h+22, now.Hour()+24*(now.Day()-5))
+			case <-time.After(time.Second):
+				t.Fatalf("tick %d: source wasn't polled", h)
+			}
+		}
+	}
+	clock.waitAfter(t, 1)
+	assert.Len(polls, 0)
+}
+
+func TestRotatorSelectionRand(t *testing.T) {
+	assert := assert.New(t)
+	calls := make(chan int, 10)
+	rot := testRotator(RotationParams{}, 3, calls, nil)
+	rot.Elems[1].Weight = 9
+	// total weight is 11: 0 -> elem 0, 1..9 -> elem 1, 10 -> elem 2
+	rot.rand = &seqRand{seq: []int{10, 0, 5}}
+	now := time.Now()
+	var got []int
+	for i := 0; i < 3; i++ {
+		rot.onTick(nil, now.Add(time.Duration(i)*time.Second))
+		got = append(got, recvCalls(calls, 1)...)
+	}
+	// elem 2 is cooling down on the second tick: total weight is 10, 0 -> elem 0;
+	// on the third tick only elem 1 is ready
+	assert.Equal([]int{2, 0, 1}, got)
+}
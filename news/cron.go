@@ -0,0 +1,161 @@
+package news
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Cron is a standard 5-field cron expression: "minute hour day-of-month month day-of-week".
+// Fields support "*", lists "1,5", ranges "1-5", steps "*/10", "8-20/2", and names for months and week days.
+type Cron struct {
+	expr                     string
+	min, hour, dom, mon, dow uint64 // bitsets
+	domStar, dowStar         bool
+	loc                      *time.Location // nil means the location of the given time
+}
+
+var monthNames = []string{"", "jan", "feb", "mar", "apr", "may", "jun", "jul", "aug", "sep", "oct", "nov", "dec"}
+
+// ParseCron parses cron expression
+func ParseCron(expr string) (*Cron, error) {
+	f := strings.Fields(expr)
+	if len(f) != 5 {
+		return nil, fmt.Errorf("cron %q: 5 fields expected", expr)
+	}
+	c := &Cron{expr: expr, domStar: f[2] == "*", dowStar: f[4] == "*"}
+	var err error
+	parse := func(dst *uint64, s string, min, max int, names []string) {
+		if err == nil {
+			*dst, err = parseCronField(s, min, max, names)
+		}
+	}
+	parse(&c.min, f[0], 0, 59, nil)
+	parse(&c.hour, f[1], 0, 23, nil)
+	parse(&c.dom, f[2], 1, 31, nil)
+	parse(&c.mon, f[3], 1, 12, monthNames)
+	parse(&c.dow, f[4], 0, 7, weekDayNames)
+	if err != nil {
+		return nil, fmt.Errorf("cron %q: %s", expr, err)
+	}
+	if c.dow&(1<<7) != 0 { // 7 is sunday too
+		c.dow |= 1
+	}
+	return c, nil
+}
+
+func parseCronField(s string, min, max int, names []string) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(s, ",") {
+		step := 1
+		if i := strings.IndexByte(part, '/'); i >= 0 {
+			var err error
+			if step, err = strconv.Atoi(part[i+1:]); err != nil || step <= 0 {
+				return 0, fmt.Errorf("bad step: %s", part)
+			}
+			part = part[:i]
+		}
+		lo, hi := min, max
+		if part != "*" {
+			rng := strings.SplitN(part, "-", 2)
+			var err error
+			if lo, err = parseCronValue(rng[0], names); err != nil {
+				return 0, err
+			}
+			hi = lo
+			if len(rng) == 2 {
+				if hi, err = parseCronValue(rng[1], names); err != nil {
+					return 0, err
+				}
+			} else if step != 1 {
+				hi = max
+			}
+		}
+		if lo < min || hi > max || lo > hi {
+			return 0, fmt.Errorf("bad range: %s", part)
+		}
+		for v := lo; v <= hi; v += step {
+			bits |= 1 << uint(v)
+		}
+	}
+	return bits, nil
+}
+
+func parseCronValue(s string, names []string) (int, error) {
+	if v, err := strconv.Atoi(s); err == nil {
+		return v, nil
+	}
+	s = strings.ToLower(s)
+	for i, n := range names {
+		if n != "" && strings.HasPrefix(s, n) {
+			return i, nil
+		}
+	}
+	return 0, fmt.Errorf("bad value: %s", s)
+}
+
+func (c *Cron) dayMatch(t time.Time) bool {
+	dom := c.dom&(1<<uint(t.Day())) != 0
+	dow := c.dow&(1<<uint(t.Weekday())) != 0
+	switch {
+	case c.domStar && c.dowStar:
+		return true
+	case c.domStar:
+		return dow
+	case c.dowStar:
+		return dom
+	}
+	// both are restricted: standard cron matches either
+	return dom || dow
+}
+
+// In returns copy of the cron that is evaluated in the given time zone
+func (c *Cron) In(loc *time.Location) *Cron {
+	cc := *c
+	cc.loc = loc
+	return &cc
+}
+
+// Next returns the first matching time (minute) strictly after t, in cron location (or t's location, if not set).
+// Zero time is returned if there is no such time within 5 years (e.g. "0 0 31 2 *").
+func (c *Cron) Next(t time.Time) time.Time {
+	if c.loc != nil {
+		t = t.In(c.loc)
+	}
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	end := t.AddDate(5, 0, 0)
+	for t.Before(end) {
+		if c.mon&(1<<uint(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if !c.dayMatch(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if c.hour&(1<<uint(t.Hour())) == 0 {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+			continue
+		}
+		if c.min&(1<<uint(t.Minute())) == 0 {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
+
+// Due - true if the cron fired after last poll (up to now), the first poll (zero last) is due immediately.
+func (c *Cron) Due(last, now time.Time) bool {
+	if last.IsZero() {
+		return true
+	}
+	next := c.Next(last)
+	return !next.IsZero() && !next.After(now)
+}
+
+func (c *Cron) String() string {
+	return c.expr
+}
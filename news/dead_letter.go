@@ -0,0 +1,156 @@
+package news
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"os"
+	"sync"
+	"time"
+)
+
+// DeadItem - item that publisher failed to send
+type DeadItem struct {
+	Pub    string
+	Error  string
+	Failed time.Time
+	*Item
+}
+
+type deadJSON struct {
+	jsonItem
+	Pub    string    `json:"pub"`
+	Error  string    `json:"error"`
+	Failed time.Time `json:"failed"`
+}
+
+// DeadLetter - persistent store (json lines file) of items that publishers failed to send.
+// Items are appended, so the file may be shared by several processes (e.g. daemon and resend command).
+type DeadLetter struct {
+	path  string
+	mu    sync.Mutex
+	taken int // number of items returned by Take
+}
+
+// NewDeadLetter creates dead letter store
+func NewDeadLetter(path string) *DeadLetter {
+	return &DeadLetter{path: path}
+}
+
+// Add stores the item that pub failed to send
+func (d *DeadLetter) Add(pub string, it *Item, err error) error {
+	return d.Put(DeadItem{Pub: pub, Error: err.Error(), Failed: time.Now(), Item: it})
+}
+
+// Put stores dead item as is
+func (d *DeadLetter) Put(di DeadItem) error {
+	line, err := json.Marshal(&deadJSON{jsonItem: toJSONItem(di.Item), Pub: di.Pub, Error: di.Error, Failed: di.Failed})
+	if err != nil {
+		return err
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return appendLine(d.path, line)
+}
+
+func appendLine(path string, line []byte) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		f.Close() // nolint:errcheck
+		return err
+	}
+	return f.Close()
+}
+
+// List returns stored items
+func (d *DeadLetter) List() ([]DeadItem, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return readDeadItems(d.path)
+}
+
+// Take returns stored items, they are moved aside till Done (items added meanwhile stay in the store).
+// If the process crashed before Done, the same items are taken again.
+func (d *DeadLetter) Take() ([]DeadItem, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	taken := d.takenPath()
+	if _, err := os.Stat(taken); os.IsNotExist(err) {
+		if err := os.Rename(d.path, taken); err != nil {
+			if os.IsNotExist(err) {
+				return nil, nil
+			}
+			return nil, err
+		}
+	}
+	items, err := readDeadItems(taken)
+	if err != nil {
+		return nil, err
+	}
+	d.taken = len(items)
+	return items, nil
+}
+
+// Done removes the taken items (they were resent or put back). The items appended to the taken file after Take
+// (by the process that opened the store before it was moved) are returned to the store.
+func (d *DeadLetter) Done() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	taken := d.takenPath()
+	data, err := os.ReadFile(taken)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	n := 0
+	for _, line := range bytes.Split(data, []byte("\n")) {
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+		if n++; n > d.taken {
+			if err := appendLine(d.path, line); err != nil {
+				return err
+			}
+		}
+	}
+	d.taken = 0
+	return os.Remove(taken)
+}
+
+func (d *DeadLetter) takenPath() string {
+	return d.path + ".taken"
+}
+
+func readDeadItems(path string) ([]DeadItem, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var items []DeadItem
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	sc.Buffer(nil, 1<<20)
+	for sc.Scan() {
+		line := bytes.TrimSpace(sc.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		var j deadJSON
+		if err := json.Unmarshal(line, &j); err != nil {
+			return nil, err
+		}
+		it, err := j.toItem()
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, DeadItem{Pub: j.Pub, Error: j.Error, Failed: j.Failed, Item: it})
+	}
+	return items, sc.Err()
+}
@@ -0,0 +1,194 @@
+package news
+
+import (
+	"bytes"
+	"errors"
+	"text/template"
+	"time"
+)
+
+// DigestStringer renders several items as one message
+type DigestStringer func(items []*Item) string
+
+// DigestTemplateDefault - default digest go template
+const DigestTemplateDefault = "{{len .Items}} news:\n{{range .Items}}• {{.Title}} {{.Link}}\n{{end}}"
+
+// digestSrc - source of digest items
+var digestSrc = &SourceInfo{Name: "digest"}
+
+// DigestData - digest template data
+type DigestData struct {
+	Items []*Item
+}
+
+// DigestGroup - items of the same source
+type DigestGroup struct {
+	Name  string
+	Items []*Item
+}
+
+// BySource groups items by source (in order of the first occurrence)
+func (d *DigestData) BySource() []DigestGroup {
+	var groups []DigestGroup
+	index := make(map[string]int)
+	for _, it := range d.Items {
+		name := it.Src.Name
+		i, ok := index[name]
+		if !ok {
+			i = len(groups)
+			index[name] = i
+			groups = append(groups, DigestGroup{Name: name})
+		}
+		groups[i].Items = append(groups[i].Items, it)
+	}
+	return groups
+}
+
+// ByFilter groups items by matched filter (in order of the first occurrence),
+// item that matched several filters is in several groups
+func (d *DigestData) ByFilter() []DigestGroup {
+	var groups []DigestGroup
+	index := make(map[string]int)
+	for _, it := range d.Items {
+		for _, name := range it.Filters {
+			i, ok := index[name]
+			if !ok {
+				i = len(groups)
+				index[name] = i
+				groups = append(groups, DigestGroup{Name: name})
+			}
+			groups[i].Items = append(groups[i].Items, it)
+		}
+	}
+	return groups
+}
+
+// NewDigestTemplateStringer - digest stringer from go template, template data is DigestData.
+// Item dates are formatted in the publisher time zone.
+func NewDigestTemplateStringer(gotmpl string, info *PubInfo) DigestStringer {
+	t := template.Must(template.New("digest-template").Funcs(tplFuncs).Parse(gotmpl))
+	return func(items []*Item) string {
+		local := make([]*Item, len(items))
+		for i, it := range items {
+			local[i] = info.localize(it)
+		}
+		buf := bytes.NewBuffer(make([]byte, 0, 1024))
+		t.Execute(buf, &DigestData{local}) //nolint:errcheck
+		return buf.String()
+	}
+}
+
+// newDigestItem - digest item with preformatted text
+func newDigestItem(text string, items []*Item) *Item {
+	return &Item{
+		ItemParams: ItemParams{Src: digestSrc, Title: "digest"},
+		Text:       text,
+		Items:      items,
+	}
+}
+
+// DigestPubParams - digest publisher params
+type DigestPubParams struct {
+	// Interval or Cron - digest period, one of them is required
+	Interval time.Duration
+	Cron     *Cron
+	// MaxItems - optional, digest is sent early when so many items are buffered
+	MaxItems       int
+	DigestStringer // DigestTemplateDefault by default
+	// QueueFile - optional, buffered items are persisted there and restored on start
+	QueueFile string
+	Clock     Clock // SystemClock by default
+}
+
+type digestPub struct {
+	Pub
+	DigestPubParams
+	buf []*Item
+}
+
+// NewDigestPub wraps publisher: items are buffered and sent as one digest message per period
+func NewDigestPub(pub Pub, p DigestPubParams) (Pub, error) {
+	if (p.Interval <= 0) == (p.Cron == nil) {
+		return nil, errors.New("digest pub: either interval or cron required")
+	}
+	if p.DigestStringer == nil {
+		p.DigestStringer = NewDigestTemplateStringer(DigestTemplateDefault, pub.Info())
+	}
+	if p.Clock == nil {
+		p.Clock = SystemClock
+	}
+	d := &digestPub{Pub: pub, DigestPubParams: p}
+	if p.QueueFile != "" {
+		items, err := loadItems(p.QueueFile)
+		if err != nil {
+			return nil, err
+		}
+		d.buf = items
+	}
+	return d, nil
+}
+
+func (d *digestPub) Publish(in <-chan *Item) {
+	out := make(chan *Item, 1)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		d.Pub.Publish(out)
+	}()
+	defer func() {
+		close(out)
+		<-done
+	}()
+	timer := d.timer()
+	for {
+		select {
+		case it, ok := <-in:
+			if !ok {
+				if d.QueueFile == "" {
+					d.flush(out) // nowhere to keep the buffer
+				}
+				return
+			}
+			d.buf = append(d.buf, it)
+			d.save()
+			if d.MaxItems > 0 && len(d.buf) >= d.MaxItems {
+				d.flush(out)
+			}
+		case <-timer:
+			d.flush(out)
+			timer = d.timer()
+		}
+	}
+}
+
+// timer fires at the end of the current period (nil channel if cron never fires)
+func (d *digestPub) timer() <-chan time.Time {
+	if d.Cron == nil {
+		return d.Clock.After(d.Interval)
+	}
+	now := d.Clock.Now()
+	next := d.Cron.Next(now)
+	if next.IsZero() {
+		return nil
+	}
+	return d.Clock.After(next.Sub(now))
+}
+
+func (d *digestPub) flush(out chan<- *Item) {
+	if len(d.buf) == 0 {
+		return
+	}
+	slog.Infow("pub_digest", "pub", d.Info().Name, "count", len(d.buf))
+	out <- newDigestItem(d.DigestStringer(d.buf), d.buf)
+	d.buf = nil
+	d.save()
+}
+
+func (d *digestPub) save() {
+	if d.QueueFile == "" {
+		return
+	}
+	if err := saveItems(d.QueueFile, d.buf); err != nil {
+		slog.Errorw("pub_digest_save_error", "pub", d.Info().Name, "err", err)
+	}
+}
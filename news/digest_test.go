@@ -0,0 +1,149 @@
+package news
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func recvItem(t *testing.T, ch <-chan *Item) *Item {
+	select {
+	case it := <-ch:
+		return it
+	case <-time.After(time.Second):
+		t.Fatal("item expected")
+	}
+	return nil
+}
+
+func TestDigestPubInterval(t *testing.T) {
+	assert := assert.New(t)
+	clock := newFakeClock(time.Date(2018, 3, 5, 10, 0, 0, 0, time.UTC))
+	cp := newChanPub("d")
+	pub, err := NewDigestPub(cp, DigestPubParams{Interval: time.Hour, MaxItems: 3, Clock: clock})
+	assert.NoError(err)
+	in := make(chan *Item)
+	done := make(chan struct{})
+	go func() {
+		pub.Publish(in)
+		close(done)
+	}()
+
+	clock.waitAfter(t, 1)
+	in <- testItem("a")
+	in <- testItem("b")
+	clock.Advance(time.Hour)
+	d := recvItem(t, cp.out)
+	assert.Len(d.Items, 2)
+	assert.Equal("2 news:\n• a http://x/a\n• b http://x/b\n", d.Text)
+
+	// nothing is sent for the empty period
+	clock.waitAfter(t, 1)
+	clock.Advance(time.Hour)
+	clock.waitAfter(t, 1)
+	assert.Len(cp.out, 0)
+
+	// max items: digest is sent early
+	for _, s := range []string{"c", "d", "e"} {
+		in <- testItem(s)
+	}
+	assert.Len(recvItem(t, cp.out).Items, 3)
+
+	// the rest is sent on close
+	in <- testItem("f")
+	close(in)
+	<-done
+	assert.Len(recvItem(t, cp.out).Items, 1)
+}
+
+func TestDigestPubCron(t *testing.T) {
+	assert := assert.New(t)
+	dir, err := os.MkdirTemp("", "digest")
+	assert.NoError(err)
+	defer os.RemoveAll(dir)
+	queue := filepath.Join(dir, "queue")
+
+	clock := newFakeClock(time.Date(2018, 3, 5, 10, 30, 0, 0, time.UTC))
+	cron, _ := ParseCron("0 * * * *")
+	params := DigestPubParams{
+		Cron:           cron,
+		QueueFile:      queue,
+		Clock:          clock,
+		DigestStringer: NewDigestTemplateStringer("{{range .ByFilter}}{{.Name}}:{{range .Items}} {{.Title}}{{end}};{{end}}", &PubInfo{}),
+	}
+	cp := newChanPub("d")
+	pub, err := NewDigestPub(cp, params)
+	assert.NoError(err)
+	in := make(chan *Item)
+	done := make(chan struct{})
+	go func() {
+		pub.Publish(in)
+		close(done)
+	}()
+	clock.waitAfter(t, 1)
+	a, b := testItem("a"), testItem("b")
+	a.Filters, b.Filters = []string{"x"}, []string{"y", "x"}
+	in <- a
+	in <- b
+	// buffer is persisted on close, not sent
+	close(in)
+	<-done
+	assert.Len(cp.out, 0)
+
+	cp = newChanPub("d")
+	pub, err = NewDigestPub(cp, params)
+	assert.NoError(err)
+	in = make(chan *Item)
+	go pub.Publish(in)
+	clock.waitAfter(t, 2) // the first pub timer is still pending
+	clock.Advance(29 * time.Minute)
+	assert.Len(cp.out, 0)
+	clock.Advance(time.Minute) // 11:00
+	d := recvItem(t, cp.out)
+	assert.Len(d.Items, 2)
+	assert.Equal("x: a b;y: b;", d.Text)
+	close(in)
+
+	_, err = NewDigestPub(cp, DigestPubParams{})
+	assert.Error(err)
+	_, err = NewDigestPub(cp, DigestPubParams{Interval: time.Hour, Cron: cron})
+	assert.Error(err)
+}
+
+func TestDigestByFilter(t *testing.T) {
+	assert := assert.New(t)
+	a, b, c := testItem("a"), testItem("b"), testItem("c")
+	a.Filters, b.Filters, c.Filters = []string{"x"}, []string{"y", "x"}, nil
+	groups := (&DigestData{Items: []*Item{a, b, c}}).ByFilter()
+	if assert.Len(groups, 2) {
+		assert.Equal("x", groups[0].Name)
+		assert.Equal([]*Item{a, b}, groups[0].Items)
+		assert.Equal("y", groups[1].Name)
+		assert.Equal([]*Item{b}, groups[1].Items)
+	}
+}
+
+func TestItemFiltersByPub(t *testing.T) {
+	assert := assert.New(t)
+	src := &funcSrc{SourceInfo: SourceInfo{Name: "src", Cooldown: time.Hour}, fn: func(sink func(*Item)) error {
+		sink(testItem("alpha beta"))
+		return nil
+	}}
+	assert.NoError(src.Check())
+	p1, p2 := newChanPub("p1"), newChanPub("p2")
+	pl := NewPipelineDefault()
+	assert.NoError(pl.AddSource(src))
+	assert.NoError(pl.AddPublisher(p1))
+	assert.NoError(pl.AddPublisher(p2))
+	assert.NoError(pl.AddFilter(&Filter{Name: "a", Cond: "alpha", Pubs: []string{"p1"}}))
+	assert.NoError(pl.AddFilter(&Filter{Name: "b", Cond: "beta", Pubs: []string{"p2"}}))
+	assert.NoError(pl.AddFilter(&Filter{Name: "ab", Cond: "alpha beta"}))
+	assert.NoError(pl.Run())
+	defer pl.Stop()
+	assert.NoError(pl.Fetch("src"))
+	assert.Equal([]string{"a", "ab"}, recvItem(t, p1.out).Filters)
+	assert.Equal([]string{"b", "ab"}, recvItem(t, p2.out).Filters)
+}
@@ -0,0 +1,288 @@
+package news
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	htmltemplate "html/template"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/dlepex/newsmaker/strext"
+)
+
+// Email connection security
+const (
+	EmailStartTLS = "starttls" // default: plain connection upgraded by STARTTLS (port 587)
+	EmailTLS      = "tls"      // implicit TLS (port 465)
+	EmailNoTLS    = "none"     // plain text, e.g. local relay
+)
+
+// Email templates defaults
+const (
+	EmailSubjectDefault = "{{if .Items}}{{len .Items}} news{{else}}{{.Title}}{{end}}"
+	EmailBodyDefault    = "{{.Message}}"
+)
+
+// EmailPubParams - SMTP email publisher params
+type EmailPubParams struct {
+	PubInfo
+	Addr     string // smtp server host:port
+	Security string // EmailStartTLS (default), EmailTLS or EmailNoTLS
+	// Username, Password - optional PLAIN auth (it requires TLS, unless server is localhost)
+	Username string
+	Password string
+	From     string
+	To       []string
+	// Subject, Body - text go templates, data is EmailData. EmailSubjectDefault and EmailBodyDefault by default.
+	Subject string
+	Body    string
+	// HTML - optional html go template of the alternative html body, data is EmailData
+	HTML         string
+	ItemStringer               // EmailData.Message of the item
+	Pause        time.Duration // pause between emails
+	Timeout      time.Duration // connection timeout, 1m by default
+	TLSConfig    *tls.Config   // optional
+}
+
+// EmailData - email templates data: item fields and the rendered message
+// (digest text for digest items, .Items are the digest items)
+type EmailData struct {
+	*Item
+	Message string
+}
+
+type emailPub struct {
+	EmailPubParams
+	host    string
+	subject *template.Template
+	body    *template.Template
+	html    *htmltemplate.Template
+}
+
+// NewEmailPub creates publisher that sends items by email
+func NewEmailPub(p EmailPubParams) (Pub, error) {
+	if strext.IsBlank(p.Addr) || strext.IsBlank(p.From) || len(p.To) == 0 {
+		return nil, errors.New("email pub: smtp address, from and to required")
+	}
+	host, _, err := net.SplitHostPort(p.Addr)
+	if err != nil {
+		return nil, fmt.Errorf("email pub: %s", err)
+	}
+	switch p.Security {
+	case "":
+		p.Security = EmailStartTLS
+	case EmailStartTLS, EmailTLS, EmailNoTLS:
+	default:
+		return nil, fmt.Errorf("email pub: unknown security: %s", p.Security)
+	}
+	for _, a := range append([]string{p.From}, p.To...) {
+		if _, err := mail.ParseAddress(a); err != nil {
+			return nil, fmt.Errorf("email pub: %q: %s", a, err)
+		}
+	}
+	if p.Subject == "" {
+		p.Subject = EmailSubjectDefault
+	}
+	if p.Body == "" {
+		p.Body = EmailBodyDefault
+	}
+	if p.ItemStringer == nil {
+		p.ItemStringer = NewItemTemplateStringer("{{.Title}}\n{{.Link}}\n{{.Src.Name}} {{.DateFmt}}")
+	}
+	if p.Timeout == 0 {
+		p.Timeout = time.Minute
+	}
+	pub := &emailPub{EmailPubParams: p, host: host}
+	if pub.subject, err = template.New("email-subject").Funcs(tplFuncs).Parse(p.Subject); err != nil {
+		return nil, fmt.Errorf("email pub: subject template: %s", err)
+	}
+	if pub.body, err = template.New("email-body").Funcs(tplFuncs).Parse(p.Body); err != nil {
+		return nil, fmt.Errorf("email pub: body template: %s", err)
+	}
+	if p.HTML != "" {
+		if pub.html, err = htmltemplate.New("email-html").Funcs(htmltemplate.FuncMap(tplFuncs)).Parse(p.HTML); err != nil {
+			return nil, fmt.Errorf("email pub: html template: %s", err)
+		}
+	}
+	return pub, nil
+}
+
+func (pub *emailPub) Info() *PubInfo {
+	return &pub.PubInfo
+}
+
+func (pub *emailPub) Publish(ch <-chan *Item) {
+	pub.PublishByOne(ch, pub.Pause, pub.send)
+}
+
+func (pub *emailPub) send(it *Item) error {
+	msg, err := pub.message(&EmailData{Item: pub.localize(it), Message: pub.render(it, pub.ItemStringer)})
+	if err != nil {
+		return err
+	}
+	c, err := pub.dial()
+	if err != nil {
+		return err
+	}
+	defer c.Close() // nolint:errcheck
+	if pub.Username != "" {
+		if err := c.Auth(smtp.PlainAuth("", pub.Username, pub.Password, pub.host)); err != nil {
+			return fmt.Errorf("smtp auth: %w", err)
+		}
+	}
+	if err := c.Mail(mailAddress(pub.From)); err != nil {
+		return fmt.Errorf("smtp mail: %w", err)
+	}
+	for _, to := range pub.To {
+		if err := c.Rcpt(mailAddress(to)); err != nil {
+			return fmt.Errorf("smtp rcpt %s: %w", to, err)
+		}
+	}
+	w, err := c.Data()
+	if err != nil {
+		return fmt.Errorf("smtp data: %w", err)
+	}
+	if _, err := w.Write(msg); err != nil {
+		return fmt.Errorf("smtp data: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("smtp data: %w", err)
+	}
+	return c.Quit()
+}
+
+// mailAddress - bare address of "Name <address>", addresses are checked by the constructor
+func mailAddress(a string) string {
+	addr, _ := mail.ParseAddress(a)
+	return addr.Address
+}
+
+// mailHeader - address as header value (the name is encoded)
+func mailHeader(a string) string {
+	addr, _ := mail.ParseAddress(a)
+	return addr.String()
+}
+
+// dial - smtp connection according to Security
+func (pub *emailPub) dial() (*smtp.Client, error) {
+	conf := pub.TLSConfig
+	if conf == nil {
+		conf = &tls.Config{ServerName: pub.host} // nolint:gosec
+	}
+	dialer := &net.Dialer{Timeout: pub.Timeout}
+	var conn net.Conn
+	var err error
+	if pub.Security == EmailTLS {
+		conn, err = tls.DialWithDialer(dialer, "tcp", pub.Addr, conf)
+	} else {
+		conn, err = dialer.Dial("tcp", pub.Addr)
+	}
+	if err != nil {
+		return nil, err
+	}
+	conn.SetDeadline(time.Now().Add(pub.Timeout)) // nolint:errcheck
+	c, err := smtp.NewClient(conn, pub.host)
+	if err != nil {
+		conn.Close() // nolint:errcheck
+		return nil, err
+	}
+	if pub.Security == EmailStartTLS {
+		if ok, _ := c.Extension("STARTTLS"); !ok {
+			c.Close() // nolint:errcheck
+			return nil, errors.New("smtp: server doesn't support STARTTLS")
+		}
+		if err := c.StartTLS(conf); err != nil {
+			c.Close() // nolint:errcheck
+			return nil, fmt.Errorf("smtp starttls: %w", err)
+		}
+	}
+	return c, nil
+}
+
+// message - email headers and body: text/plain or multipart/alternative (with html)
+func (pub *emailPub) message(data *EmailData) ([]byte, error) {
+	var subj, body, html bytes.Buffer
+	if err := pub.subject.Execute(&subj, data); err != nil {
+		return nil, fmt.Errorf("email subject: %s", err)
+	}
+	if err := pub.body.Execute(&body, data); err != nil {
+		return nil, fmt.Errorf("email body: %s", err)
+	}
+	if pub.html != nil {
+		if err := pub.html.Execute(&html, data); err != nil {
+			return nil, fmt.Errorf("email html: %s", err)
+		}
+	}
+	var msg bytes.Buffer
+	h := func(k, v string) {
+		fmt.Fprintf(&msg, "%s: %s\r\n", k, v)
+	}
+	to := make([]string, len(pub.To))
+	for i, a := range pub.To {
+		to[i] = mailHeader(a)
+	}
+	h("From", mailHeader(pub.From))
+	h("To", strings.Join(to, ", "))
+	h("Subject", mime.QEncoding.Encode("utf-8", strings.Join(strings.Fields(subj.String()), " ")))
+	h("Date", time.Now().Format(time.RFC1123Z))
+	h("Message-ID", pub.messageID())
+	h("MIME-Version", "1.0")
+	if pub.html == nil {
+		h("Content-Type", "text/plain; charset=utf-8")
+		h("Content-Transfer-Encoding", "quoted-printable")
+		msg.WriteString("\r\n")
+		if err := writeQP(&msg, body.Bytes()); err != nil {
+			return nil, err
+		}
+		return msg.Bytes(), nil
+	}
+	mw := multipart.NewWriter(&msg)
+	h("Content-Type", "multipart/alternative; boundary="+mw.Boundary())
+	msg.WriteString("\r\n")
+	for _, part := range []struct {
+		ctype string
+		data  []byte
+	}{{"text/plain", body.Bytes()}, {"text/html", html.Bytes()}} {
+		w, err := mw.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.ctype + "; charset=utf-8"},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		if err := writeQP(w, part.data); err != nil {
+			return nil, err
+		}
+	}
+	if err := mw.Close(); err != nil {
+		return nil, err
+	}
+	return msg.Bytes(), nil
+}
+
+func (pub *emailPub) messageID() string {
+	b := make([]byte, 12)
+	rand.Read(b) // nolint:errcheck
+	return fmt.Sprintf("<%s@%s>", hex.EncodeToString(b), pub.host)
+}
+
+func writeQP(w io.Writer, data []byte) error {
+	qp := quotedprintable.NewWriter(w)
+	if _, err := qp.Write(data); err != nil {
+		return err
+	}
+	return qp.Close()
+}
@@ -0,0 +1,259 @@
+package news
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/http/httptest"
+	"net/mail"
+	"net/textproto"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeSMTP - local stand-in of smtp server: STARTTLS (if tls is set), AUTH PLAIN, single recipient reply code
+type fakeSMTP struct {
+	ln    net.Listener
+	tls   *tls.Config
+	rcpt  int // RCPT reply code, 250 by default
+	mu    sync.Mutex
+	mails []fakeMail
+}
+
+type fakeMail struct {
+	From string
+	To   []string
+	Data string
+	TLS  bool
+	Auth string
+}
+
+// newFakeSMTP starts the server, implicit - TLS connections (tls.Listen)
+func newFakeSMTP(t *testing.T, implicit bool) (*fakeSMTP, *tls.Config) {
+	// httptest certificate is valid for 127.0.0.1
+	hs := httptest.NewTLSServer(nil)
+	hs.Close()
+	pool := x509.NewCertPool()
+	pool.AddCert(hs.Certificate())
+	f := &fakeSMTP{tls: &tls.Config{Certificates: hs.TLS.Certificates}}
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if implicit {
+		ln = tls.NewListener(ln, f.tls)
+	}
+	f.ln = ln
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go f.serve(conn, implicit)
+		}
+	}()
+	return f, &tls.Config{RootCAs: pool, ServerName: "127.0.0.1"}
+}
+
+func (f *fakeSMTP) serve(conn net.Conn, secure bool) {
+	defer conn.Close()
+	tc := textproto.NewConn(conn)
+	tc.PrintfLine("220 fake ESMTP")
+	m := fakeMail{TLS: secure}
+	for {
+		line, err := tc.ReadLine()
+		if err != nil {
+			return
+		}
+		cmd, arg := line, ""
+		if i := strings.IndexByte(line, ' '); i > 0 {
+			cmd, arg = line[:i], line[i+1:]
+		}
+		switch strings.ToUpper(cmd) {
+		case "EHLO", "HELO":
+			if f.tls != nil && !m.TLS {
+				tc.PrintfLine("250-fake")
+				tc.PrintfLine("250-STARTTLS")
+			} else {
+				tc.PrintfLine("250-fake")
+			}
+			tc.PrintfLine("250 AUTH PLAIN")
+		case "STARTTLS":
+			tc.PrintfLine("220 ready")
+			conn = tls.Server(conn, f.tls)
+			tc = textproto.NewConn(conn)
+			m.TLS = true
+		case "AUTH":
+			b, _ := base64.StdEncoding.DecodeString(strings.TrimPrefix(arg, "PLAIN "))
+			m.Auth = string(b)
+			tc.PrintfLine("235 ok")
+		case "MAIL":
+			m.From = arg
+			tc.PrintfLine("250 ok")
+		case "RCPT":
+			f.mu.Lock()
+			code := f.rcpt
+			f.mu.Unlock()
+			if code != 0 {
+				tc.PrintfLine("%d rejected", code)
+				continue
+			}
+			m.To = append(m.To, arg)
+			tc.PrintfLine("250 ok")
+		case "DATA":
+			tc.PrintfLine("354 go ahead")
+			data, _ := tc.ReadDotBytes()
+			m.Data = string(data)
+			f.mu.Lock()
+			f.mails = append(f.mails, m)
+			f.mu.Unlock()
+			tc.PrintfLine("250 ok")
+		case "QUIT":
+			tc.PrintfLine("221 bye")
+			return
+		default:
+			tc.PrintfLine("250 ok")
+		}
+	}
+}
+
+func (f *fakeSMTP) received() []fakeMail {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]fakeMail(nil), f.mails...)
+}
+
+// mailParts - decoded message parts by content type
+func mailParts(t *testing.T, data string) (*mail.Message, map[string]string) {
+	msg, err := mail.ReadMessage(strings.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	res := make(map[string]string)
+	ct, params, _ := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	if !strings.HasPrefix(ct, "multipart/") {
+		body, _ := io.ReadAll(quotedprintable.NewReader(msg.Body))
+		res[ct] = string(body)
+		return msg, res
+	}
+	mr := multipart.NewReader(msg.Body, params["boundary"])
+	for {
+		p, err := mr.NextPart()
+		if err != nil {
+			break
+		}
+		pct, _, _ := mime.ParseMediaType(p.Header.Get("Content-Type"))
+		body, _ := io.ReadAll(p) // quoted-printable is decoded by multipart reader
+		res[pct] = string(body)
+	}
+	return msg, res
+}
+
+func TestEmailPubStartTLS(t *testing.T) {
+	assert := assert.New(t)
+	srv, tlsConf := newFakeSMTP(t, false)
+	pub, err := NewEmailPub(EmailPubParams{
+		PubInfo:   PubInfo{Name: "mail"},
+		Addr:      srv.ln.Addr().String(),
+		Username:  "user",
+		Password:  "secret",
+		From:      "Новости <news@example.com>",
+		To:        []string{"a@example.com", "B <b@example.com>"},
+		Subject:   "[news] {{.Title}}",
+		HTML:      `<a href="{{.Link}}">{{.Title}}</a>`,
+		TLSConfig: tlsConf,
+	})
+	assert.NoError(err)
+	ep := pub.(*emailPub)
+	it := testItem("Привет & <мир>")
+	assert.NoError(ep.send(it))
+	mails := srv.received()
+	if !assert.Len(mails, 1) {
+		return
+	}
+	m := mails[0]
+	assert.True(m.TLS)
+	assert.Equal("\x00user\x00secret", m.Auth)
+	assert.Equal("FROM:<news@example.com>", m.From)
+	assert.Equal([]string{"TO:<a@example.com>", "TO:<b@example.com>"}, m.To)
+
+	msg, parts := mailParts(t, m.Data)
+	subj, _ := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+	assert.Equal("[news] Привет & <мир>", subj)
+	from, err := msg.Header.AddressList("From")
+	if assert.NoError(err) {
+		assert.Equal("Новости", from[0].Name)
+	}
+	assert.Equal("Привет & <мир>\nhttp://x/Привет & <мир>\nsrc ", parts["text/plain"])
+	assert.Equal(`<a href="http://x/%d0%9f%d1%80%d0%b8%d0%b2%d0%b5%d1%82%20&amp;%20%3c%d0%bc%d0%b8%d1%80%3e">Привет &amp; &lt;мир&gt;</a>`, parts["text/html"])
+}
+
+func TestEmailPubDigest(t *testing.T) {
+	assert := assert.New(t)
+	srv, tlsConf := newFakeSMTP(t, true)
+	pub, err := NewEmailPub(EmailPubParams{
+		PubInfo:   PubInfo{Name: "mail"},
+		Addr:      srv.ln.Addr().String(),
+		Security:  EmailTLS,
+		From:      "news@example.com",
+		To:        []string{"a@example.com"},
+		TLSConfig: tlsConf,
+	})
+	assert.NoError(err)
+	d := newDigestItem("a\nb", []*Item{testItem("a"), testItem("b")})
+	assert.NoError(pub.(*emailPub).send(d))
+	if mails := srv.received(); assert.Len(mails, 1) {
+		msg, parts := mailParts(t, mails[0].Data)
+		assert.Equal("2 news", msg.Header.Get("Subject"))
+		assert.Equal("a\nb", strings.TrimRight(parts["text/plain"], "\n"))
+	}
+}
+
+func TestEmailPubErrors(t *testing.T) {
+	assert := assert.New(t)
+	srv, _ := newFakeSMTP(t, false)
+	params := EmailPubParams{
+		PubInfo:  PubInfo{Name: "mail"},
+		Addr:     srv.ln.Addr().String(),
+		Security: EmailNoTLS,
+		From:     "news@example.com",
+		To:       []string{"a@example.com"},
+	}
+	pub, err := NewEmailPub(params)
+	assert.NoError(err)
+	var retry RetryPolicy
+	for code, transient := range map[int]bool{451: true, 550: false} {
+		srv.mu.Lock()
+		srv.rcpt = code
+		srv.mu.Unlock()
+		err := pub.(*emailPub).send(testItem("x"))
+		assert.Error(err)
+		assert.Equal(transient, retry.retryable(err), fmt.Sprint(code))
+	}
+	// no STARTTLS support
+	params.Security = ""
+	pub, _ = NewEmailPub(params)
+	assert.Error(pub.(*emailPub).send(testItem("x")))
+
+	for _, p := range []EmailPubParams{
+		{Addr: "localhost:25", To: []string{"a@example.com"}},
+		{Addr: "localhost", From: "x@example.com", To: []string{"a@example.com"}},
+		{Addr: "localhost:25", From: "x@example.com", To: []string{"not an address"}},
+		{Addr: "localhost:25", From: "x@example.com", To: []string{"a@example.com"}, Security: "ssl"},
+		{Addr: "localhost:25", From: "x@example.com", To: []string{"a@example.com"}, HTML: "{{"},
+	} {
+		_, err := NewEmailPub(p)
+		assert.Error(err)
+	}
+}
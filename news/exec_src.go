@@ -0,0 +1,107 @@
+package news
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// ExecSrcParams - command source params.
+type ExecSrcParams struct {
+	SourceInfo
+	Command []string // program and its args
+	Dir     string   // working dir, optional
+	Env     []string // extra "KEY=value" environment, optional
+	Timeout time.Duration
+}
+
+type execSrc struct {
+	ExecSrcParams
+}
+
+// NewExecSrc creates source that runs external command (scraper) on each rotation.
+// The command writes json lines to stdout (see jsonItem), stderr is logged.
+func NewExecSrc(p ExecSrcParams) (Source, error) {
+	if len(p.Command) == 0 || p.Command[0] == "" {
+		return nil, errors.New("exec src: command required")
+	}
+	if p.Timeout == 0 {
+		p.Timeout = time.Minute
+	}
+	if err := p.Check(); err != nil {
+		return nil, err
+	}
+	slog.Debugw("created source", "src", p.Name, "cd", p.Cooldown, "command", p.Command, "mute-hours", p.MuteInterval)
+	return &execSrc{p}, nil
+}
+
+func (src *execSrc) Info() *SourceInfo {
+	return &src.SourceInfo
+}
+
+func (src *execSrc) Receive(sink func(*Item)) error {
+	ctx, cancel := context.WithTimeout(context.Background(), src.Timeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, src.Command[0], src.Command[1:]...)
+	cmd.Dir = src.Dir
+	cmd.WaitDelay = time.Second // killed command's children may keep stderr open
+	if len(src.Env) != 0 {
+		cmd.Env = append(cmd.Environ(), src.Env...)
+	}
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	count := 0
+	sc := bufio.NewScanner(stdout)
+	sc.Buffer(nil, 1<<20)
+	for sc.Scan() {
+		line := bytes.TrimSpace(sc.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		var j jsonItem
+		if err := json.Unmarshal(line, &j); err != nil {
+			slog.Errorw("exec_parse_error", "src", src.Name, "err", err, "line", string(line))
+			continue
+		}
+		item, err := NewItem(j.toParams(&src.SourceInfo))
+		if err != nil {
+			slog.Errorw("exec_parse_error", "src", src.Name, "err", err, "line", string(line))
+			continue
+		}
+		count++
+		sink(item)
+	}
+	scanErr := sc.Err() // e.g. too long line
+	if scanErr != nil {
+		// the rest of stdout isn't read, so the command (or its children) may block on write
+		cancel()
+		stdout.Close() // nolint:errcheck
+	}
+	err = cmd.Wait()
+	for _, line := range strings.Split(strings.TrimSpace(stderr.String()), "\n") {
+		if line != "" {
+			slog.Warnw("exec_stderr", "src", src.Name, "line", line)
+		}
+	}
+	slog.Debugw("exec_receive", "src", src.Name, "count", count)
+	if scanErr != nil {
+		return fmt.Errorf("exec src: read output: %s", scanErr)
+	}
+	if ctx.Err() == context.DeadlineExceeded {
+		return errors.New("exec src: timeout")
+	}
+	return err
+}
@@ -0,0 +1,44 @@
+package news
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExecSrc(t *testing.T) {
+	script := `echo '{"title": "Scraped one", "link": "http://s/1", "categories": ["a"]}'
+echo 'not json'
+echo '{"title": "Scraped two", "published": "2018-03-01T10:00:00Z"}'
+echo 'warning' >&2`
+	src, err := NewExecSrc(ExecSrcParams{SourceInfo: SourceInfo{Name: "exec"}, Command: []string{"sh", "-c", script}})
+	assert.NoError(t, err)
+	var items []*Item
+	assert.NoError(t, src.Receive(func(it *Item) { items = append(items, it) }))
+	if assert.Len(t, items, 2) {
+		assert.Equal(t, "Scraped one", items[0].Title)
+		assert.Equal(t, []string{"a"}, items[0].Categories)
+		assert.NotNil(t, items[1].Published)
+	}
+
+	src, _ = NewExecSrc(ExecSrcParams{SourceInfo: SourceInfo{Name: "exec"}, Command: []string{"sh", "-c", "exit 3"}})
+	assert.Error(t, src.Receive(func(*Item) {}))
+
+	// line longer than the scanner buffer (1 MiB): the items before it are received, the command is killed
+	long := `echo '{"title": "Before"}'; head -c 2000000 /dev/zero | tr '\0' a; echo; sleep 5`
+	src, _ = NewExecSrc(ExecSrcParams{SourceInfo: SourceInfo{Name: "exec"}, Command: []string{"sh", "-c", long}})
+	items = nil
+	start := time.Now()
+	err = src.Receive(func(it *Item) { items = append(items, it) })
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "too long")
+	}
+	assert.Len(t, items, 1)
+	assert.True(t, time.Since(start) < 3*time.Second)
+
+	src, _ = NewExecSrc(ExecSrcParams{SourceInfo: SourceInfo{Name: "exec"}, Command: []string{"sleep", "5"}, Timeout: 100 * time.Millisecond})
+	start = time.Now()
+	assert.Error(t, src.Receive(func(*Item) {}))
+	assert.True(t, time.Since(start) < 3*time.Second)
+}
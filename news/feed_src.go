@@ -1,12 +1,18 @@
 package news
 
 import (
+	"bytes"
 	"errors"
+	"fmt"
+	"io"
 	"math/rand"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
 	gfd "github.com/mmcdole/gofeed"
+	ext "github.com/mmcdole/gofeed/extensions"
 )
 
 //FeedSrcParams -
@@ -14,11 +20,15 @@ type FeedSrcParams struct {
 	SourceInfo
 	Links  []string
 	Client *http.Client
+	// WebSub - optional subscriber, if set feeds with hubs are subscribed and not polled while subscription is active.
+	WebSub *WebSub
 }
 
 type feedSrc struct {
 	FeedSrcParams
 	links []string
+	hint  time.Duration // poll hint of the last Receive
+	push  func(*Item)   // sink of WebSub content, see setSink
 }
 
 // FeedSrcDebug - log extra information
@@ -43,7 +53,7 @@ func NewFeedSrc(p FeedSrcParams) (Source, error) {
 		return nil, err
 	}
 	slog.Debugw("created source", "src", p.Name, "cd", p.Cooldown, "links", p.Links, "mute-hours", p.MuteInterval)
-	return &feedSrc{p, append([]string{}, p.Links...)}, nil
+	return &feedSrc{FeedSrcParams: p, links: append([]string{}, p.Links...)}, nil
 }
 
 func (src *feedSrc) Info() *SourceInfo {
@@ -51,8 +61,7 @@ func (src *feedSrc) Info() *SourceInfo {
 }
 
 //shuffleLinks uses Sattolo's algorithm
-func (src *feedSrc) shuffleLinks() []string {
-	links := src.links
+func shuffleLinks(links []string) []string {
 	for i := len(links); i > 1; {
 		i--
 		j := rand.Intn(i)
@@ -61,17 +70,117 @@ func (src *feedSrc) shuffleLinks() []string {
 	return links
 }
 
+// receiveLinks - calls receiveOne for each link (in random order), pausing between link requests.
+func receiveLinks(links []string, receiveOne func(link string)) {
+	for i, link := range shuffleLinks(links) {
+		if i != 0 {
+			pause := FeedSrcPause
+			if FeedSrcPauseRand > 0 {
+				pause += time.Duration(rand.Int63n(int64(FeedSrcPauseRand)))
+			}
+			time.Sleep(pause)
+		}
+		receiveOne(link)
+	}
+}
+
+// setSink - sinkHolder impl.
+func (src *feedSrc) setSink(sink func(*Item)) {
+	src.push = sink
+}
+
+// PollHint - PollHinter impl.
+func (src *feedSrc) PollHint() time.Duration {
+	return src.hint
+}
+
 func (src *feedSrc) Receive(sink func(*Item)) error {
-	for _, link := range src.shuffleLinks() {
-		src.ReceiveOne(link, sink)
-		time.Sleep(FeedSrcPause + time.Duration(rand.Int63n(int64(FeedSrcPauseRand))))
+	src.hint = 0
+	links := src.links
+	if src.WebSub != nil {
+		links = make([]string, 0, len(src.links))
+		for _, link := range src.links {
+			if !src.WebSub.Active(link) {
+				links = append(links, link)
+			}
+		}
 	}
+	receiveLinks(links, func(link string) {
+		src.ReceiveOne(link, sink)
+	})
 	return nil
 }
 
+func (src *feedSrc) fetch(link string) ([]byte, error) {
+	r, err := src.Client.Get(link)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Body.Close() // nolint:errcheck
+	if !(200 <= r.StatusCode && r.StatusCode < 300) {
+		return nil, fmt.Errorf("bad http status: %v (%s)", r.StatusCode, r.Status)
+	}
+	src.addHint(cacheControlMaxAge(r.Header.Get("Cache-Control")))
+	return io.ReadAll(r.Body)
+}
+
+// addHint - the largest hint of all links wins
+func (src *feedSrc) addHint(h time.Duration) {
+	if h > src.hint {
+		src.hint = h
+	}
+}
+
 func (src *feedSrc) ReceiveOne(link string, sink func(*Item)) {
-	p := gfd.NewParser()
-	feed, err := p.ParseURL(link)
+	data, err := src.fetch(link)
+	if err != nil {
+		slog.Errorw(err.Error(), "src", src.Name, "link", link)
+		return
+	}
+	if gfd.DetectFeedType(bytes.NewReader(data)) == gfd.FeedTypeUnknown {
+		// link may be a site page: discover its feed, the link is replaced by the feed url
+		feedLink, err := discoverFeed(link, data)
+		if err != nil {
+			slog.Errorw("feed_discover_error", "src", src.Name, "link", link, "err", err)
+			return
+		}
+		slog.Infow("feed_discovered", "src", src.Name, "link", link, "feed", feedLink)
+		src.replaceLink(link, feedLink)
+		link = feedLink
+		if data, err = src.fetch(link); err != nil {
+			slog.Errorw(err.Error(), "src", src.Name, "link", link)
+			return
+		}
+	}
+	src.addHint(feedPollHint(data))
+	if src.WebSub != nil {
+		if hub, self := findFeedHub(data); hub != "" {
+			if self == "" {
+				self = link
+			}
+			push := src.push
+			if push == nil {
+				push = sink
+			}
+			// content is delivered later by http handler, so it doesn't go to the sink of this Receive
+			src.WebSub.Subscribe(link, self, hub, func(content []byte) {
+				src.receiveData(link, content, push)
+			})
+		}
+	}
+	src.receiveData(link, data, sink)
+}
+
+func (src *feedSrc) replaceLink(old, link string) {
+	for i, l := range src.links {
+		if l == old {
+			src.links[i] = link
+		}
+	}
+}
+
+func (src *feedSrc) receiveData(link string, data []byte, sink func(*Item)) {
+	feed, err := gfd.NewParser().Parse(bytes.NewReader(data))
 	if err != nil {
 		slog.Errorw(err.Error(), "src", src.Name, "link", link)
 		return
@@ -84,13 +193,7 @@ func (src *feedSrc) ReceiveOne(link string, sink func(*Item)) {
 		v.Description = ""
 		src.debug("item", v)
 
-		params := ItemParams{
-			Link:      v.Link,
-			Title:     v.Title,
-			Published: v.PublishedParsed,
-			Src:       &src.SourceInfo,
-		}
-
+		params := feedItemParams(&src.SourceInfo, feed, v)
 		item, err := NewItem(params)
 		if err != nil {
 			slog.Errorw("feed_parse_error", "err", err, "link", v.Link, "params", params)
@@ -100,6 +203,62 @@ func (src *feedSrc) ReceiveOne(link string, sink func(*Item)) {
 	}
 }
 
+// feedItemParams converts gofeed item to item params
+func feedItemParams(info *SourceInfo, feed *gfd.Feed, v *gfd.Item) ItemParams {
+	p := ItemParams{
+		Link:       v.Link,
+		Title:      v.Title,
+		Categories: v.Categories,
+		Published:  v.PublishedParsed,
+		Updated:    v.UpdatedParsed,
+		GUID:       v.GUID,
+		FeedTitle:  feed.Title,
+		Src:        info,
+	}
+	if len(v.Authors) != 0 && v.Authors[0] != nil {
+		p.Author = v.Authors[0].Name
+	} else if v.Author != nil {
+		p.Author = v.Author.Name
+	}
+	for _, e := range v.Enclosures {
+		if e == nil || e.URL == "" {
+			continue
+		}
+		length, _ := strconv.ParseInt(e.Length, 10, 64)
+		p.Enclosures = append(p.Enclosures, Enclosure{URL: e.URL, Type: e.Type, Length: length})
+	}
+	if v.Image != nil {
+		p.Image = v.Image.URL
+	}
+	if p.Image == "" {
+		p.Image = feedItemImage(v, p.Enclosures)
+	}
+	return p
+}
+
+// feedItemImage - the first image enclosure or media:content/media:thumbnail image
+func feedItemImage(v *gfd.Item, encl []Enclosure) string {
+	for _, e := range encl {
+		if strings.HasPrefix(e.Type, "image/") {
+			return e.URL
+		}
+	}
+	media := v.Extensions["media"]
+	for _, name := range []string{"content", "thumbnail", "group"} {
+		for _, x := range media[name] {
+			exts := append([]ext.Extension{x}, x.Children["content"]...)
+			exts = append(exts, x.Children["thumbnail"]...)
+			for _, x := range exts {
+				url, medium, typ := x.Attrs["url"], x.Attrs["medium"], x.Attrs["type"]
+				if url != "" && (x.Name == "thumbnail" || medium == "image" || strings.HasPrefix(typ, "image/")) {
+					return url
+				}
+			}
+		}
+	}
+	return ""
+}
+
 func (src *feedSrc) debug(what string, value interface{}) {
 	if FeedSrcDebug {
 		slog.Debugw("feed_debug", "src", src.Name, "what", what, "value", value)
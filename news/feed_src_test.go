@@ -0,0 +1,48 @@
+package news
+
+import (
+	"strings"
+	"testing"
+
+	gfd "github.com/mmcdole/gofeed"
+	"github.com/stretchr/testify/assert"
+)
+
+const richFeed = `<?xml version="1.0"?>
+<rss version="2.0" xmlns:media="http://search.yahoo.com/mrss/" xmlns:dc="http://purl.org/dc/elements/1.1/"><channel>
+<title>Agency News</title>
+<item>
+  <title>With enclosure</title><link>http://x/1</link><guid>urn:x:1</guid>
+  <author>john@x.org (John)</author><category>politics</category>
+  <pubDate>Thu, 01 Mar 2018 10:20:00 +0300</pubDate>
+  <enclosure url="http://x/1.mp3" type="audio/mpeg" length="100"/>
+  <enclosure url="http://x/1.jpg" type="image/jpeg" length="200"/>
+</item>
+<item>
+  <title>With media</title><link>http://x/2</link><dc:creator>Jane</dc:creator>
+  <media:thumbnail url="http://x/2-thumb.jpg"/>
+</item>
+</channel></rss>`
+
+func TestFeedItemParams(t *testing.T) {
+	feed, err := gfd.NewParser().Parse(strings.NewReader(richFeed))
+	assert.NoError(t, err)
+	info := &SourceInfo{Name: "rich"}
+
+	p := feedItemParams(info, feed, feed.Items[0])
+	assert.Equal(t, "Agency News", p.FeedTitle)
+	assert.Equal(t, "urn:x:1", p.GUID)
+	assert.Equal(t, "John", p.Author)
+	assert.Equal(t, []string{"politics"}, p.Categories)
+	assert.Equal(t, "http://x/1.jpg", p.Image)
+	assert.Equal(t, []Enclosure{{"http://x/1.mp3", "audio/mpeg", 100}, {"http://x/1.jpg", "image/jpeg", 200}}, p.Enclosures)
+
+	p = feedItemParams(info, feed, feed.Items[1])
+	assert.Equal(t, "Jane", p.Author)
+	assert.Equal(t, "http://x/2-thumb.jpg", p.Image)
+
+	it, err := NewItem(p)
+	assert.NoError(t, err)
+	s := NewItemTemplateStringer("{{.FeedTitle}}: {{.Title}} by {{.Author}} {{.Image}}")(it)
+	assert.Equal(t, "Agency News: With media by Jane http://x/2-thumb.jpg", s)
+}
@@ -0,0 +1,137 @@
+package news
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/dlepex/newsmaker/strext"
+	gfd "github.com/mmcdole/gofeed"
+)
+
+// FileSrcParams - local directory source params.
+// Files with ".jsonl" or ".json" extension are json lines (see jsonItem), all others are parsed as rss/atom feeds.
+type FileSrcParams struct {
+	SourceInfo
+	Dir     string
+	Pattern string // file name glob, "*" by default
+	// Emitted - tracks already emitted entries (by guid, or by link and title), NewDedup(8192) by default
+	Emitted Deduplicator
+}
+
+type fileSrc struct {
+	FileSrcParams
+	stamps map[string]fileStamp // files already read
+}
+
+type fileStamp struct {
+	mod  time.Time
+	size int64
+}
+
+// NewFileSrc creates source that polls local directory: new or changed files are read on each Receive,
+// entries that were already emitted are skipped.
+func NewFileSrc(p FileSrcParams) (Source, error) {
+	if strext.IsBlank(p.Dir) {
+		return nil, errors.New("file src: dir required")
+	}
+	if p.Pattern == "" {
+		p.Pattern = "*"
+	}
+	if _, err := filepath.Match(p.Pattern, ""); err != nil {
+		return nil, err
+	}
+	if p.Emitted == nil {
+		p.Emitted = NewDedup(8192)
+	}
+	if err := p.Check(); err != nil {
+		return nil, err
+	}
+	slog.Debugw("created source", "src", p.Name, "cd", p.Cooldown, "dir", p.Dir, "pattern", p.Pattern, "mute-hours", p.MuteInterval)
+	return &fileSrc{p, make(map[string]fileStamp)}, nil
+}
+
+func (src *fileSrc) Info() *SourceInfo {
+	return &src.SourceInfo
+}
+
+func (src *fileSrc) Receive(sink func(*Item)) error {
+	paths, err := filepath.Glob(filepath.Join(src.Dir, src.Pattern))
+	if err != nil {
+		return err
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		fi, err := os.Stat(path)
+		if err != nil || fi.IsDir() {
+			continue
+		}
+		stamp := fileStamp{fi.ModTime(), fi.Size()}
+		if src.stamps[path] == stamp {
+			continue
+		}
+		params, err := src.readFile(path)
+		if err != nil {
+			slog.Errorw("file_parse_error", "src", src.Name, "path", path, "err", err)
+			continue
+		}
+		src.stamps[path] = stamp
+		slog.Debugw("file_receive", "path", path, "count", len(params))
+		for _, p := range params {
+			key := StrToDedupKey(p.Link, p.Title)
+			if p.GUID != "" {
+				key = StrToDedupKey("guid", p.GUID)
+			}
+			if !src.Emitted.Keep(key) {
+				continue
+			}
+			item, err := NewItem(p)
+			if err != nil {
+				slog.Errorw("file_parse_error", "src", src.Name, "path", path, "err", err, "params", p)
+				continue
+			}
+			sink(item)
+		}
+	}
+	return nil
+}
+
+func (src *fileSrc) readFile(path string) ([]ItemParams, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var params []ItemParams
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".jsonl", ".json":
+		sc := bufio.NewScanner(bytes.NewReader(data))
+		sc.Buffer(nil, 1<<20)
+		for sc.Scan() {
+			line := bytes.TrimSpace(sc.Bytes())
+			if len(line) == 0 {
+				continue
+			}
+			var j jsonItem
+			if err := json.Unmarshal(line, &j); err != nil {
+				return nil, err
+			}
+			params = append(params, j.toParams(&src.SourceInfo))
+		}
+		return params, sc.Err()
+	default:
+		feed, err := gfd.NewParser().Parse(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		for _, v := range feed.Items {
+			params = append(params, feedItemParams(&src.SourceInfo, feed, v))
+		}
+		return params, nil
+	}
+}
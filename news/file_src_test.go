@@ -0,0 +1,45 @@
+package news
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const fileSrcFeed = `<?xml version="1.0"?>
+<rss version="2.0"><channel><title>t</title>
+<item><title>Feed one</title><link>http://x/1</link></item>
+<item><title>Feed two</title><link>http://x/2</link></item>
+</channel></rss>`
+
+func TestFileSrc(t *testing.T) {
+	dir, err := os.MkdirTemp("", "filesrc")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	write := func(name, data string, mod time.Time) {
+		path := filepath.Join(dir, name)
+		assert.NoError(t, os.WriteFile(path, []byte(data), 0600))
+		assert.NoError(t, os.Chtimes(path, mod, mod))
+	}
+	t0 := time.Now().Add(-time.Hour)
+	write("a.xml", fileSrcFeed, t0)
+	write("b.jsonl", `{"title": "Json one", "link": "http://j/1"}`+"\n\n"+`{"title": "Json two", "published": "2018-03-01T10:00:00Z"}`, t0)
+
+	src, err := NewFileSrc(FileSrcParams{SourceInfo: SourceInfo{Name: "file"}, Dir: dir})
+	assert.NoError(t, err)
+	receive := func() (titles []string) {
+		assert.NoError(t, src.Receive(func(it *Item) { titles = append(titles, it.Title) }))
+		return
+	}
+	assert.Equal(t, []string{"Feed one", "Feed two", "Json one", "Json two"}, receive())
+	assert.Empty(t, receive(), "unchanged files are not read")
+
+	write("b.jsonl", `{"title": "Json one", "link": "http://j/1"}`+"\n"+`{"title": "Json three"}`, t0.Add(time.Minute))
+	assert.Equal(t, []string{"Json three"}, receive(), "only new entries of changed file")
+
+	write("c.json", `{broken`, t0)
+	assert.Empty(t, receive())
+}
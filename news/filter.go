@@ -7,6 +7,7 @@ import (
 )
 
 type Filter struct { //nolint
+	Name    string // optional, Cond by default (digest grouping)
 	Cond    string
 	Sources []string // this are "globs" (glob is a simplified pattern, right now it's either prefix or suffix match)
 	Pubs    []string // same
@@ -21,6 +22,9 @@ func (f *Filter) init() error {
 		return e
 	}
 	f.dnf = dnf
+	if f.Name == "" {
+		f.Name = f.Cond
+	}
 	return nil
 }
 
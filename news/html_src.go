@@ -0,0 +1,146 @@
+package news
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/dlepex/newsmaker/strext"
+)
+
+// HTMLSrcParams - html page scraping source params.
+// All selectors are CSS selectors, ItemSel is required, others are relative to the item container.
+type HTMLSrcParams struct {
+	SourceInfo
+	Links  []string
+	Client *http.Client
+
+	ItemSel  string // item container
+	TitleSel string // title element, if empty the container text is used
+	LinkSel  string // link element (href attr), if empty the container itself (if it is <a>) or its first <a href>
+	DateSel  string // optional, date element: `datetime` attr is preferred, text otherwise
+	// DateLayout - time.Parse layout of the date, required if DateSel is set
+	DateLayout string
+}
+
+type htmlSrc struct {
+	HTMLSrcParams
+	links []string
+}
+
+// NewHTMLSrc creates html scraping source
+func NewHTMLSrc(p HTMLSrcParams) (Source, error) {
+	if p.Client == nil {
+		p.Client = http.DefaultClient
+	}
+	if len(p.Links) == 0 {
+		return nil, errors.New("html src: no links")
+	}
+	if strext.IsBlank(p.ItemSel) {
+		return nil, errors.New("html src: item selector required")
+	}
+	if p.DateSel != "" && p.DateLayout == "" {
+		return nil, errors.New("html src: date layout required")
+	}
+	if err := p.Check(); err != nil {
+		return nil, err
+	}
+	slog.Debugw("created source", "src", p.Name, "cd", p.Cooldown, "links", p.Links, "item", p.ItemSel, "mute-hours", p.MuteInterval)
+	return &htmlSrc{p, append([]string{}, p.Links...)}, nil
+}
+
+func (src *htmlSrc) Info() *SourceInfo {
+	return &src.SourceInfo
+}
+
+func (src *htmlSrc) Receive(sink func(*Item)) error {
+	receiveLinks(src.links, func(link string) {
+		if err := src.ReceiveOne(link, sink); err != nil {
+			slog.Errorw(err.Error(), "src", src.Name, "link", link)
+		}
+	})
+	return nil
+}
+
+func (src *htmlSrc) ReceiveOne(link string, sink func(*Item)) error {
+	base, err := url.Parse(link)
+	if err != nil {
+		return err
+	}
+	r, err := src.Client.Get(link)
+	if err != nil {
+		return err
+	}
+	defer r.Body.Close() // nolint:errcheck
+	if !(200 <= r.StatusCode && r.StatusCode < 300) {
+		return fmt.Errorf("bad http status: %v (%s)", r.StatusCode, r.Status)
+	}
+	doc, err := goquery.NewDocumentFromReader(r.Body)
+	if err != nil {
+		return err
+	}
+	sel := doc.Find(src.ItemSel)
+	slog.Debugw("html_receive", "link", link, "count", sel.Length())
+	sel.Each(func(_ int, s *goquery.Selection) {
+		params := src.parseItem(base, s)
+		item, err := NewItem(params)
+		if err != nil {
+			slog.Errorw("html_parse_error", "err", err, "link", link, "params", params)
+			return
+		}
+		sink(item)
+	})
+	return nil
+}
+
+func (src *htmlSrc) parseItem(base *url.URL, s *goquery.Selection) ItemParams {
+	params := ItemParams{Src: &src.SourceInfo}
+	title := s
+	if src.TitleSel != "" {
+		title = s.Find(src.TitleSel).First()
+	}
+	params.Title = strings.Join(strings.Fields(title.Text()), " ")
+
+	var a *goquery.Selection
+	switch {
+	case src.LinkSel != "":
+		a = s.Find(src.LinkSel).First()
+	case goquery.NodeName(s) == "a":
+		a = s
+	default:
+		a = s.Find("a[href]").First()
+	}
+	if href, ok := a.Attr("href"); ok {
+		if u, err := base.Parse(strings.TrimSpace(href)); err == nil {
+			params.Link = u.String()
+		}
+	}
+
+	if src.DateSel != "" {
+		d := s.Find(src.DateSel).First()
+		text, ok := d.Attr("datetime")
+		if !ok {
+			text = d.Text()
+		}
+		loc := src.Location // dates without zone are in the source time zone
+		if loc == nil {
+			loc = time.Local
+		}
+		if t, err := time.ParseInLocation(src.DateLayout, strings.TrimSpace(text), loc); err == nil {
+			params.Published = &t
+		} else {
+			src.debug("date", err)
+		}
+	}
+	return params
+}
+
+func (src *htmlSrc) debug(what string, value interface{}) {
+	if FeedSrcDebug {
+		slog.Debugw("html_debug", "src", src.Name, "what", what, "value", value)
+	}
+}
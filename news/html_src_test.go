@@ -0,0 +1,69 @@
+package news
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const htmlSrcPage = `<html><body>
+<div class="news">
+  <div class="item"><a class="t" href="/news/1">First   news</a><time datetime="2018-03-01 10:20">1 March</time></div>
+  <div class="item"><a class="t" href="https://other.org/2">Second news</a><time>02.03.2018 11:00</time></div>
+  <div class="item"><span class="t"> </span></div>
+</div>
+</body></html>`
+
+func TestHTMLSrc(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(htmlSrcPage)) // nolint:errcheck
+	}))
+	defer srv.Close()
+
+	src, err := NewHTMLSrc(HTMLSrcParams{
+		SourceInfo: SourceInfo{Name: "html"},
+		Links:      []string{srv.URL + "/list/"},
+		ItemSel:    ".news .item",
+		TitleSel:   ".t",
+		DateSel:    "time",
+		DateLayout: "2006-01-02 15:04",
+	})
+	assert.NoError(t, err)
+
+	var items []*Item
+	assert.NoError(t, src.Receive(func(it *Item) { items = append(items, it) }))
+	assert.Len(t, items, 2)
+	assert.Equal(t, "First news", items[0].Title)
+	assert.Equal(t, srv.URL+"/news/1", items[0].Link)
+	if assert.NotNil(t, items[0].Published) {
+		assert.Equal(t, 20, items[0].Published.Minute())
+	}
+	assert.Equal(t, "https://other.org/2", items[1].Link)
+	assert.Nil(t, items[1].Published)
+
+	// the date is in the source time zone
+	src, err = NewHTMLSrc(HTMLSrcParams{
+		SourceInfo: SourceInfo{Name: "html", Location: time.FixedZone("UTC+3", 3*3600)},
+		Links:      []string{srv.URL + "/list/"},
+		ItemSel:    ".news .item",
+		TitleSel:   ".t",
+		DateSel:    "time",
+		DateLayout: "2006-01-02 15:04",
+	})
+	assert.NoError(t, err)
+	items = nil
+	assert.NoError(t, src.Receive(func(it *Item) { items = append(items, it) }))
+	if assert.NotEmpty(t, items) && assert.NotNil(t, items[0].Published) {
+		assert.True(t, items[0].Published.Equal(time.Date(2018, 3, 1, 7, 20, 0, 0, time.UTC)), items[0].Published)
+	}
+}
+
+func TestHTMLSrcParams(t *testing.T) {
+	_, err := NewHTMLSrc(HTMLSrcParams{SourceInfo: SourceInfo{Name: "x"}, Links: []string{"http://x"}})
+	assert.Error(t, err)
+	_, err = NewHTMLSrc(HTMLSrcParams{SourceInfo: SourceInfo{Name: "x"}, Links: []string{"http://x"}, ItemSel: "li", DateSel: "time"})
+	assert.Error(t, err)
+}
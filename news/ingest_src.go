@@ -0,0 +1,174 @@
+package news
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/dlepex/newsmaker/strext"
+)
+
+// IngestSrcParams - http ingest (webhook) source params.
+type IngestSrcParams struct {
+	SourceInfo
+	Addr  string // listen address e.g. ":8081"
+	Path  string // endpoint path, "/" by default
+	Token string // required, expected in "Authorization: Bearer <token>" header
+	// MaxBody - max request body size in bytes (1MB by default)
+	MaxBody int64
+}
+
+type ingestSrc struct {
+	IngestSrcParams
+}
+
+// jsonItem - json representation of item, used by push/exec sources.
+type jsonItem struct {
+	Title      string     `json:"title"`
+	Link       string     `json:"link"`
+	Published  *time.Time `json:"published"`
+	Categories []string   `json:"categories"`
+	Updated    *time.Time `json:"updated"`
+	GUID       string     `json:"guid"`
+	Author     string     `json:"author"`
+	Image      string     `json:"image"`
+	Src        string     `json:"src,omitempty"`     // source name, used by item stores only
+	Text       string     `json:"text,omitempty"`    // preformatted message (digest), used by item stores only
+	Filters    []string   `json:"filters,omitempty"` // matched filters, used by item stores only
+}
+
+func (j *jsonItem) toParams(src *SourceInfo) ItemParams {
+	return ItemParams{
+		Src:        src,
+		Title:      j.Title,
+		Link:       j.Link,
+		Categories: j.Categories,
+		Published:  j.Published,
+		Updated:    j.Updated,
+		GUID:       j.GUID,
+		Author:     j.Author,
+		Image:      j.Image,
+	}
+}
+
+// decodeJSONItems decodes either single json object or json array of objects
+func decodeJSONItems(data []byte) ([]jsonItem, error) {
+	s := strings.TrimSpace(string(data))
+	if strings.HasPrefix(s, "[") {
+		var items []jsonItem
+		err := json.Unmarshal([]byte(s), &items)
+		return items, err
+	}
+	var it jsonItem
+	if err := json.Unmarshal([]byte(s), &it); err != nil {
+		return nil, err
+	}
+	return []jsonItem{it}, nil
+}
+
+// NewIngestSrc creates push source: http endpoint that accepts POST-ed json items,
+// either single object or array: {"title": "", "link": "", "published": "RFC3339", "categories": []}
+func NewIngestSrc(p IngestSrcParams) (Source, error) {
+	if strext.IsBlank(p.Addr) {
+		return nil, errors.New("ingest src: listen address required")
+	}
+	if strext.IsBlank(p.Token) {
+		return nil, errors.New("ingest src: token required")
+	}
+	if p.Path == "" {
+		p.Path = "/"
+	}
+	if p.MaxBody <= 0 {
+		p.MaxBody = 1 << 20
+	}
+	if err := p.Check(); err != nil {
+		return nil, err
+	}
+	slog.Debugw("created source", "src", p.Name, "addr", p.Addr, "path", p.Path)
+	return &ingestSrc{p}, nil
+}
+
+func (src *ingestSrc) Info() *SourceInfo {
+	return &src.SourceInfo
+}
+
+// Receive - does nothing, items are pushed
+func (src *ingestSrc) Receive(sink func(*Item)) error {
+	return nil
+}
+
+func (src *ingestSrc) Listen(sink func(*Item), quit <-chan struct{}) error {
+	mux := http.NewServeMux()
+	mux.Handle(src.Path, src.handler(sink))
+	srv := &http.Server{Addr: src.Addr, Handler: mux}
+	errc := make(chan error, 1)
+	go func() {
+		errc <- srv.ListenAndServe()
+	}()
+	slog.Infow("ingest_listen", "src", src.Name, "addr", src.Addr, "path", src.Path)
+	select {
+	case err := <-errc:
+		return err
+	case <-quit:
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		return srv.Shutdown(ctx)
+	}
+}
+
+func (src *ingestSrc) authorized(r *http.Request) bool {
+	return bearerAuthorized(r, src.Token)
+}
+
+// bearerAuthorized - the request has "Authorization: Bearer <token>" header
+func bearerAuthorized(r *http.Request, token string) bool {
+	h := r.Header.Get("Authorization")
+	if !strings.HasPrefix(h, "Bearer ") {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(strings.TrimPrefix(h, "Bearer ")), []byte(token)) == 1
+}
+
+func (src *ingestSrc) handler(sink func(*Item)) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if !src.authorized(r) {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, src.MaxBody))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+			return
+		}
+		jitems, err := decodeJSONItems(data)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		items := make([]*Item, 0, len(jitems))
+		for i := range jitems {
+			item, err := NewItem(jitems[i].toParams(&src.SourceInfo))
+			if err != nil {
+				http.Error(w, fmt.Sprintf("item %d: %s", i, err), http.StatusBadRequest)
+				return
+			}
+			items = append(items, item)
+		}
+		for _, it := range items {
+			sink(it)
+		}
+		slog.Debugw("ingest_receive", "src", src.Name, "count", len(items))
+		w.WriteHeader(http.StatusAccepted)
+		fmt.Fprintf(w, `{"accepted":%d}`, len(items))
+	})
+}
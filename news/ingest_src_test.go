@@ -0,0 +1,77 @@
+package news
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIngestSrc(t *testing.T) {
+	s, err := NewIngestSrc(IngestSrcParams{
+		SourceInfo: SourceInfo{Name: "push"},
+		Addr:       ":0",
+		Token:      "secret",
+	})
+	assert.NoError(t, err)
+	var items []*Item
+	h := s.(*ingestSrc).handler(func(it *Item) { items = append(items, it) })
+
+	post := func(token, body string) int {
+		r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+		r.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w.Code
+	}
+
+	assert.Equal(t, http.StatusUnauthorized, post("wrong", `{"title": "a"}`))
+	// the token without "Bearer " prefix
+	r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"title": "a"}`))
+	r.Header.Set("Authorization", "secret")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Equal(t, http.StatusAccepted, post("secret", `{"title": "single", "link": "http://x/1"}`))
+	assert.Equal(t, http.StatusAccepted, post("secret", `[{"title": "b1", "published": "2018-03-01T10:00:00Z"}, {"title": "b2"}]`))
+	assert.Equal(t, http.StatusBadRequest, post("secret", `[{"title": "ok"}, {"link": "no title"}]`))
+	assert.Equal(t, http.StatusBadRequest, post("secret", `{`))
+
+	if assert.Len(t, items, 3) {
+		assert.Equal(t, "single", items[0].Title)
+		assert.Equal(t, "http://x/1", items[0].Link)
+		assert.NotNil(t, items[1].Published)
+		assert.Equal(t, "push", items[2].Src.Name)
+	}
+}
+
+// listenSrc - test push source, Listen hands out the sink and waits for quit
+type listenSrc struct {
+	funcSrc
+	sinks chan func(*Item)
+}
+
+func (s *listenSrc) Listen(sink func(*Item), quit <-chan struct{}) error {
+	s.sinks <- sink
+	<-quit
+	return nil
+}
+
+func TestPushSinkAfterStop(t *testing.T) {
+	src := &listenSrc{funcSrc: funcSrc{SourceInfo: SourceInfo{Name: "src"}}, sinks: make(chan func(*Item), 1)}
+	pub := newChanPub("pub")
+	pl := NewPipelineDefault()
+	assert.NoError(t, pl.AddSource(src))
+	assert.NoError(t, pl.AddPublisher(pub))
+	assert.NoError(t, pl.AddFilter(&Filter{Cond: "alpha"}))
+	assert.NoError(t, pl.Run())
+	sink := <-src.sinks
+	sink(testItem("alpha 1"))
+	assert.Equal(t, "alpha 1", recvItem(t, pub.out).Title)
+	pl.Stop()
+	// e.g. http handler that is still running: the item is dropped, closed channel isn't written
+	assert.NotPanics(t, func() { sink(testItem("alpha 2")) })
+	pl.Wait()
+}
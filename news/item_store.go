@@ -0,0 +1,128 @@
+package news
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+)
+
+// itemsToJSON - json lines of items (see jsonItem), source is stored by name
+func itemsToJSON(items []*Item) ([]byte, error) {
+	var buf bytes.Buffer
+	e := json.NewEncoder(&buf)
+	for _, it := range items {
+		j := toJSONItem(it)
+		if err := e.Encode(&j); err != nil {
+			return nil, err
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+func toJSONItem(it *Item) jsonItem {
+	j := jsonItem{
+		Title:      it.Title,
+		Link:       it.Link,
+		Published:  it.Published,
+		Categories: it.Categories,
+		Updated:    it.Updated,
+		GUID:       it.GUID,
+		Author:     it.Author,
+		Image:      it.Image,
+		Text:       it.Text,
+		Filters:    it.Filters,
+	}
+	if it.Src != nil {
+		j.Src = it.Src.Name
+	}
+	return j
+}
+
+// toItem - restores stored item, its source is only named: it is not the pipeline source
+func (j *jsonItem) toItem() (*Item, error) {
+	it, err := NewItem(j.toParams(&SourceInfo{Name: j.Src}))
+	if err != nil {
+		return nil, err
+	}
+	it.Text, it.Filters = j.Text, j.Filters
+	return it, nil
+}
+
+// saveItems - atomically (re)writes items file, empty items remove the file
+func saveItems(path string, items []*Item) error {
+	if len(items) == 0 {
+		err := os.Remove(path)
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	data, err := itemsToJSON(items)
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()           // nolint:errcheck
+		os.Remove(tmp.Name()) // nolint:errcheck
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name()) // nolint:errcheck
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// appendItems - appends items to items file
+func appendItems(path string, items []*Item) error {
+	data, err := itemsToJSON(items)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close() // nolint:errcheck
+		return err
+	}
+	return f.Close()
+}
+
+// loadItems - reads items file, missing file means no items.
+// Sources of loaded items are only named: they are not the pipeline sources.
+func loadItems(path string) ([]*Item, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var items []*Item
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	sc.Buffer(nil, 1<<20)
+	for sc.Scan() {
+		line := bytes.TrimSpace(sc.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		var j jsonItem
+		if err := json.Unmarshal(line, &j); err != nil {
+			return nil, err
+		}
+		it, err := j.toItem()
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, it)
+	}
+	return items, sc.Err()
+}
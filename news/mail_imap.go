@@ -0,0 +1,87 @@
+package news
+
+import (
+	"io"
+
+	"github.com/emersion/go-imap"
+	"github.com/emersion/go-imap/client"
+)
+
+// IMAPBox - imap mailbox: unseen messages are processed and then flagged as seen.
+type IMAPBox struct {
+	Addr     string // host:port
+	User     string
+	Password string
+	Mailbox  string // "INBOX" by default
+	StartTLS bool   // plain connection upgraded with STARTTLS, implicit TLS otherwise
+}
+
+func (box *IMAPBox) dial() (*client.Client, error) {
+	if !box.StartTLS {
+		return client.DialTLS(box.Addr, nil)
+	}
+	c, err := client.Dial(box.Addr)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.StartTLS(nil); err != nil {
+		c.Logout() // nolint:errcheck
+		return nil, err
+	}
+	return c, nil
+}
+
+// Process - Mailbox impl.
+func (box *IMAPBox) Process(fn func(raw []byte)) error {
+	c, err := box.dial()
+	if err != nil {
+		return err
+	}
+	defer c.Logout() // nolint:errcheck
+	if err := c.Login(box.User, box.Password); err != nil {
+		return err
+	}
+	name := box.Mailbox
+	if name == "" {
+		name = "INBOX"
+	}
+	if _, err := c.Select(name, false); err != nil {
+		return err
+	}
+	crit := imap.NewSearchCriteria()
+	crit.WithoutFlags = []string{imap.SeenFlag}
+	uids, err := c.UidSearch(crit)
+	if err != nil || len(uids) == 0 {
+		return err
+	}
+	seqset := new(imap.SeqSet)
+	seqset.AddNum(uids...)
+
+	section := &imap.BodySectionName{Peek: true}
+	msgs := make(chan *imap.Message, 16)
+	done := make(chan error, 1)
+	go func() {
+		done <- c.UidFetch(seqset, []imap.FetchItem{section.FetchItem(), imap.FetchUid}, msgs)
+	}()
+	processed := new(imap.SeqSet)
+	for m := range msgs {
+		body := m.GetBody(section)
+		if body == nil {
+			continue
+		}
+		raw, err := io.ReadAll(body)
+		if err != nil {
+			continue
+		}
+		fn(raw)
+		processed.AddNum(m.Uid)
+	}
+	if err := <-done; err != nil {
+		return err
+	}
+	if processed.Empty() {
+		return nil
+	}
+	flags := []interface{}{imap.SeenFlag}
+	return c.UidStore(processed, imap.FormatFlagsOp(imap.AddFlags, true), flags, nil)
+}
@@ -0,0 +1,270 @@
+package news
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+	"golang.org/x/net/html/charset"
+)
+
+// Mailbox - mail storage read by mail source.
+// Process calls fn for each unprocessed message (raw RFC 822 message), then marks them processed.
+type Mailbox interface {
+	Process(fn func(raw []byte)) error
+}
+
+// MailSrcMode - how mail messages are turned into items
+type MailSrcMode string
+
+const (
+	// MailSubject - one item per message: subject is the title, first link of the body is the link
+	MailSubject MailSrcMode = "subject"
+	// MailLinks - one item per link of the body (newsletters, google alerts): link text is the title
+	MailLinks MailSrcMode = "links"
+)
+
+// MailSrcParams - mail (newsletter) source params
+type MailSrcParams struct {
+	SourceInfo
+	Mailbox Mailbox
+	Mode    MailSrcMode
+	// MinTitleWords - (links mode) links with shorter text are skipped e.g. "unsubscribe", 3 by default.
+	MinTitleWords int
+}
+
+type mailSrc struct {
+	MailSrcParams
+}
+
+// NewMailSrc creates mail source
+func NewMailSrc(p MailSrcParams) (Source, error) {
+	if p.Mailbox == nil {
+		return nil, errors.New("mail src: mailbox required")
+	}
+	switch p.Mode {
+	case "":
+		p.Mode = MailSubject
+	case MailSubject, MailLinks:
+	default:
+		return nil, fmt.Errorf("mail src: unknown mode: %s", p.Mode)
+	}
+	if p.MinTitleWords == 0 {
+		p.MinTitleWords = 3
+	}
+	if err := p.Check(); err != nil {
+		return nil, err
+	}
+	slog.Debugw("created source", "src", p.Name, "cd", p.Cooldown, "mode", p.Mode, "mute-hours", p.MuteInterval)
+	return &mailSrc{p}, nil
+}
+
+func (src *mailSrc) Info() *SourceInfo {
+	return &src.SourceInfo
+}
+
+func (src *mailSrc) Receive(sink func(*Item)) error {
+	return src.Mailbox.Process(func(raw []byte) {
+		msg, err := parseMail(raw)
+		if err != nil {
+			slog.Errorw("mail_parse_error", "src", src.Name, "err", err)
+			return
+		}
+		for _, params := range src.toParams(msg) {
+			item, err := NewItem(params)
+			if err != nil {
+				slog.Errorw("mail_parse_error", "src", src.Name, "err", err, "subject", msg.subject)
+				continue
+			}
+			sink(item)
+		}
+	})
+}
+
+func (src *mailSrc) toParams(msg *mailMsg) []ItemParams {
+	links := msg.links()
+	if src.Mode == MailSubject {
+		p := ItemParams{Src: &src.SourceInfo, Title: msg.subject, Published: msg.date}
+		if len(links) != 0 {
+			p.Link = links[0].href
+		}
+		return []ItemParams{p}
+	}
+	var ps []ItemParams
+	seen := make(map[string]bool)
+	for _, l := range links {
+		if seen[l.href] || len(strings.Fields(l.text)) < src.MinTitleWords {
+			continue
+		}
+		seen[l.href] = true
+		ps = append(ps, ItemParams{Src: &src.SourceInfo, Title: l.text, Link: l.href, Published: msg.date})
+	}
+	return ps
+}
+
+// mailMsg - parsed mail message (only what we need)
+type mailMsg struct {
+	subject string
+	date    *time.Time
+	html    string
+	text    string
+}
+
+type mailLink struct {
+	text, href string
+}
+
+var mailWordDecoder = &mime.WordDecoder{CharsetReader: charset.NewReaderLabel}
+
+func parseMail(raw []byte) (*mailMsg, error) {
+	m, err := mail.ReadMessage(bytes.NewReader(raw))
+	if err != nil {
+		return nil, err
+	}
+	msg := &mailMsg{}
+	if msg.subject, err = mailWordDecoder.DecodeHeader(m.Header.Get("Subject")); err != nil {
+		msg.subject = m.Header.Get("Subject")
+	}
+	if d, err := m.Header.Date(); err == nil {
+		msg.date = &d
+	}
+	err = msg.readPart(m.Header.Get("Content-Type"), m.Header.Get("Content-Transfer-Encoding"), m.Body)
+	return msg, err
+}
+
+func (msg *mailMsg) readPart(contentType, encoding string, body io.Reader) error {
+	mt, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		mt, params = "text/plain", nil
+	}
+	switch strings.ToLower(encoding) {
+	case "base64":
+		body = base64.NewDecoder(base64.StdEncoding, body)
+	case "quoted-printable":
+		body = quotedprintable.NewReader(body)
+	}
+	if strings.HasPrefix(mt, "multipart/") {
+		mr := multipart.NewReader(body, params["boundary"])
+		for {
+			p, err := mr.NextPart()
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			if err := msg.readPart(p.Header.Get("Content-Type"), p.Header.Get("Content-Transfer-Encoding"), p); err != nil {
+				return err
+			}
+		}
+	}
+	if mt != "text/html" && mt != "text/plain" {
+		return nil
+	}
+	if cs := params["charset"]; cs != "" {
+		if body, err = charset.NewReaderLabel(cs, body); err != nil {
+			return err
+		}
+	}
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return err
+	}
+	if mt == "text/html" && msg.html == "" {
+		msg.html = string(data)
+	} else if mt == "text/plain" && msg.text == "" {
+		msg.text = string(data)
+	}
+	return nil
+}
+
+var textURLRegexp = regexp.MustCompile(`https?://[^\s<>"]+`)
+
+// links - links of html part or, if there is no html, of the text part.
+func (msg *mailMsg) links() []mailLink {
+	var links []mailLink
+	if msg.html != "" {
+		doc, err := goquery.NewDocumentFromReader(strings.NewReader(msg.html))
+		if err == nil {
+			doc.Find("a[href]").Each(func(_ int, a *goquery.Selection) {
+				href, _ := a.Attr("href")
+				if !strings.HasPrefix(href, "http") {
+					return
+				}
+				links = append(links, mailLink{strings.Join(strings.Fields(a.Text()), " "), unwrapRedirect(href)})
+			})
+			return links
+		}
+	}
+	// text: the link title is the rest of the line, or the previous non-empty line
+	prev := ""
+	for _, line := range strings.Split(msg.text, "\n") {
+		line = strings.TrimSpace(line)
+		for _, href := range textURLRegexp.FindAllString(line, -1) {
+			text := strings.TrimSpace(strings.Replace(line, href, "", -1))
+			if text == "" {
+				text = prev
+			}
+			links = append(links, mailLink{strings.Join(strings.Fields(text), " "), unwrapRedirect(href)})
+		}
+		if line != "" {
+			prev = line
+		}
+	}
+	return links
+}
+
+// unwrapRedirect - extracts target url from the redirect links (google alerts uses google.com/url?url=...)
+func unwrapRedirect(href string) string {
+	u, err := url.Parse(href)
+	if err != nil || u.Path != "/url" || !strings.Contains(u.Host, "google.") {
+		return href
+	}
+	q := u.Query()
+	for _, k := range []string{"url", "q"} {
+		if t := q.Get(k); strings.HasPrefix(t, "http") {
+			return t
+		}
+	}
+	return href
+}
+
+// Maildir - local maildir mailbox: messages are read from "new" and moved to "cur" with seen flag.
+type Maildir string
+
+// Process - Mailbox impl.
+func (dir Maildir) Process(fn func(raw []byte)) error {
+	newDir, curDir := filepath.Join(string(dir), "new"), filepath.Join(string(dir), "cur")
+	files, err := os.ReadDir(newDir)
+	if err != nil {
+		return err
+	}
+	for _, f := range files {
+		if f.IsDir() || strings.HasPrefix(f.Name(), ".") {
+			continue
+		}
+		path := filepath.Join(newDir, f.Name())
+		raw, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		fn(raw)
+		if err := os.Rename(path, filepath.Join(curDir, f.Name()+":2,S")); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,76 @@
+package news
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const mailAlert = "From: alerts@google.com\r\n" +
+	"Subject: =?UTF-8?B?R29vZ2xlINCe0L/QvtCy0LXRidC10L3QuNGP?=\r\n" +
+	"Date: Thu, 01 Mar 2018 10:20:00 +0300\r\n" +
+	"MIME-Version: 1.0\r\n" +
+	"Content-Type: multipart/alternative; boundary=\"b1\"\r\n" +
+	"\r\n" +
+	"--b1\r\n" +
+	"Content-Type: text/plain; charset=UTF-8\r\n" +
+	"\r\n" +
+	"plain text is ignored if there is html\r\n" +
+	"--b1\r\n" +
+	"Content-Type: text/html; charset=UTF-8\r\n" +
+	"Content-Transfer-Encoding: quoted-printable\r\n" +
+	"\r\n" +
+	"<a href=3D\"https://www.google.com/url?rct=3Dj&url=3Dhttps://news.org/a&ct=3Dga\">Central <b>bank</b> raises rate</a>\r\n" +
+	"<a href=3D\"https://news.org/b\">Markets fall again today</a>\r\n" +
+	"<a href=3D\"https://www.google.com/alerts/remove\">Unsubscribe</a>\r\n" +
+	"--b1--\r\n"
+
+const mailPlain = "Subject: Weekly digest\r\n" +
+	"Content-Type: text/plain; charset=koi8-r\r\n" +
+	"\r\n" +
+	"Read more: https://news.org/weekly\r\n"
+
+func TestMailSrc(t *testing.T) {
+	dir, err := os.MkdirTemp("", "maildir")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	for _, d := range []string{"new", "cur", "tmp"} {
+		assert.NoError(t, os.Mkdir(filepath.Join(dir, d), 0700))
+	}
+	write := func(name, msg string) {
+		assert.NoError(t, os.WriteFile(filepath.Join(dir, "new", name), []byte(msg), 0600))
+	}
+	receive := func(mode MailSrcMode) []*Item {
+		src, err := NewMailSrc(MailSrcParams{SourceInfo: SourceInfo{Name: "mail"}, Mailbox: Maildir(dir), Mode: mode})
+		assert.NoError(t, err)
+		var items []*Item
+		assert.NoError(t, src.Receive(func(it *Item) { items = append(items, it) }))
+		return items
+	}
+
+	write("1", mailAlert)
+	write("2", mailPlain)
+	items := receive(MailSubject)
+	if assert.Len(t, items, 2) {
+		assert.Equal(t, "Google Оповещения", items[0].Title)
+		assert.Equal(t, "https://news.org/a", items[0].Link)
+		assert.NotNil(t, items[0].Published)
+		assert.Equal(t, "Weekly digest", items[1].Title)
+		assert.Equal(t, "https://news.org/weekly", items[1].Link)
+	}
+	assert.Len(t, receive(MailSubject), 0, "processed messages are moved to cur")
+	cur, _ := os.ReadDir(filepath.Join(dir, "cur"))
+	assert.Len(t, cur, 2)
+	assert.True(t, strings.HasSuffix(cur[0].Name(), ":2,S"))
+
+	write("3", mailAlert)
+	items = receive(MailLinks)
+	if assert.Len(t, items, 2) {
+		assert.Equal(t, "Central bank raises rate", items[0].Title)
+		assert.Equal(t, "https://news.org/a", items[0].Link)
+		assert.Equal(t, "https://news.org/b", items[1].Link)
+	}
+}
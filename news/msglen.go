@@ -0,0 +1,372 @@
+package news
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// LengthPolicy - what to do with the message longer than PubInfo.MaxLength
+type LengthPolicy string
+
+// Length policies
+const (
+	LengthTruncate LengthPolicy = "truncate" // default: message is cut, ellipsis is appended
+	LengthSplit    LengthPolicy = "split"    // message is sent as several messages
+)
+
+// Ellipsis - appended to the truncated message
+const Ellipsis = "…"
+
+func (p LengthPolicy) check(info *PubInfo) error {
+	switch p {
+	case "", LengthTruncate, LengthSplit:
+	default:
+		return fmt.Errorf("pub %s: unknown length policy: %s", info.Name, p)
+	}
+	if info.MaxLength < 0 {
+		return fmt.Errorf("pub %s: negative max length", info.Name)
+	}
+	m, ok := NormalizeMarkup(info.Markup)
+	if !ok {
+		return fmt.Errorf("pub %s: unknown markup: %s", info.Name, info.Markup)
+	}
+	info.Markup = m
+	return nil
+}
+
+// NormalizeMarkup - markup name as in Telegram parse_mode (the case is ignored), false if the markup is unknown
+func NormalizeMarkup(m string) (string, bool) {
+	for _, k := range []string{TgPlain, TgMarkdown, TgMarkdownV2, TgHTML} {
+		if strings.EqualFold(m, k) {
+			return k, true
+		}
+	}
+	return m, false
+}
+
+// fit - message parts within MaxLength
+func (info *PubInfo) fit(text string) []string {
+	if info.MaxLength <= 0 {
+		return []string{text}
+	}
+	return fitMessage(text, info.MaxLength, info.Markup, info.Length == LengthSplit, utf8.RuneCountInString)
+}
+
+// messages - item message(s): rendered and fitted within MaxLength
+func (info *PubInfo) messages(it *Item, str ItemStringer) []string {
+	return info.fit(info.render(it, str))
+}
+
+// partSender - sends the message parts of the item (see publishParts), the parts sent before the failed one
+// aren't sent again when the item is retried. Nil sender just sends all the parts (without rate limiting).
+type partSender struct {
+	wait   func()
+	waited bool  // wait was called for the next request already, see waitOnce
+	item   *Item // the item that was sent partially
+	sent   int
+}
+
+// waitOnce - waits before the request that isn't a part (e.g. photo),
+// the first part of the same attempt doesn't wait again
+func (ps *partSender) waitOnce() {
+	if ps != nil && ps.wait != nil && !ps.waited {
+		ps.wait()
+		ps.waited = true
+	}
+}
+
+func (ps *partSender) send(it *Item, parts []string, send func(string) error) error {
+	if ps == nil {
+		ps = &partSender{}
+	}
+	if ps.item != it {
+		ps.item, ps.sent = it, 0
+	}
+	for ; ps.sent < len(parts); ps.sent++ {
+		if ps.waited {
+			ps.waited = false
+		} else if ps.wait != nil {
+			ps.wait()
+		}
+		if err := send(parts[ps.sent]); err != nil {
+			return err
+		}
+	}
+	ps.item = nil
+	return nil
+}
+
+// entity - formatting entity of the markup: bold, link, html tag etc
+type entity struct {
+	open, close string
+}
+
+// cutPoint - position where the message can be cut without breaking markup:
+// not within escape sequence, link or tag. Open entities are closed at the cut and reopened in the next part.
+type cutPoint struct {
+	pos  int // byte offset
+	size int // size of text before pos
+	open []entity
+}
+
+func entitiesSize(es []entity, closing bool, size func(string) int) int {
+	n := 0
+	for _, e := range es {
+		if closing {
+			n += size(e.close)
+		} else {
+			n += size(e.open)
+		}
+	}
+	return n
+}
+
+func closeEntities(es []entity) string {
+	var b strings.Builder
+	for i := len(es) - 1; i >= 0; i-- {
+		b.WriteString(es[i].close)
+	}
+	return b.String()
+}
+
+func openEntities(es []entity) string {
+	var b strings.Builder
+	for _, e := range es {
+		b.WriteString(e.open)
+	}
+	return b.String()
+}
+
+// fitMessage cuts the message into parts of max size at most (only the first part is kept with ellipsis, unless split).
+// The size is additive, e.g. rune count. The cut is made before the line break or the space if possible,
+// markup entities are closed and reopened.
+func fitMessage(text string, max int, markup string, split bool, size func(string) int) []string {
+	var parts []string
+	extra := 0
+	if !split {
+		extra = size(Ellipsis)
+	}
+	for size(text) > max {
+		pts := cutPoints(text, markup, size)
+		best, space, line := -1, -1, -1
+		for i, p := range pts {
+			if p.size+extra > max {
+				break
+			}
+			if p.pos == 0 || p.size+extra+entitiesSize(p.open, true, size) > max || p.size <= entitiesSize(p.open, false, size) {
+				continue
+			}
+			best = i
+			if 2*p.size >= max {
+				switch text[p.pos] {
+				case ' ':
+					space = i
+				case '\n':
+					line = i
+				}
+			}
+		}
+		var part, rest string
+		switch {
+		case best < 0: // no room for the markup: hard cut
+			end := sizeOffset(text, max-extra, size)
+			part, rest = text[:end], text[end:]
+			if !split {
+				part += Ellipsis
+			}
+		default:
+			if line >= 0 {
+				best = line
+			} else if space >= 0 {
+				best = space
+			}
+			p := pts[best]
+			part = strings.TrimRight(text[:p.pos], " \n")
+			if !split {
+				part += Ellipsis
+			}
+			part += closeEntities(p.open)
+			rest = openEntities(p.open) + strings.TrimLeft(text[p.pos:], " \n")
+		}
+		if !split {
+			return []string{part}
+		}
+		if strings.TrimSpace(part) != "" {
+			parts = append(parts, part)
+		}
+		text = rest
+	}
+	if strings.TrimSpace(text) != "" || len(parts) == 0 {
+		parts = append(parts, text)
+	}
+	return parts
+}
+
+// sizeOffset - byte offset of the longest prefix of at most max size (at least one rune)
+func sizeOffset(s string, max int, size func(string) int) int {
+	n := 0
+	for i, r := range s {
+		n += size(string(r))
+		if n > max && i > 0 {
+			return i
+		}
+	}
+	return len(s)
+}
+
+// cutPoints - positions where the text can be cut, the last one is the end of the text
+func cutPoints(text, markup string, size func(string) int) []cutPoint {
+	switch markup {
+	case TgHTML:
+		return htmlCutPoints(text, size)
+	case TgMarkdown:
+		return mdCutPoints(text, false, size)
+	case TgMarkdownV2:
+		return mdCutPoints(text, true, size)
+	}
+	pts := make([]cutPoint, 0, len(text)+1)
+	n := 0
+	for i, r := range text {
+		pts = append(pts, cutPoint{pos: i, size: n})
+		n += size(string(r))
+	}
+	return append(pts, cutPoint{pos: len(text), size: n})
+}
+
+// htmlCutPoints - tags and character references aren't broken
+func htmlCutPoints(text string, size func(string) int) []cutPoint {
+	var pts []cutPoint
+	var open []entity
+	n := 0
+	for i := 0; i < len(text); {
+		pts = append(pts, cutPoint{i, n, open})
+		end := i
+		switch text[i] {
+		case '<':
+			if j := strings.IndexByte(text[i:], '>'); j > 0 {
+				end = i + j + 1
+				tag := text[i:end]
+				name := tagName(tag)
+				switch {
+				case strings.HasPrefix(tag, "</"):
+					open = popEntity(open, "</"+name+">")
+				case !strings.HasSuffix(tag, "/>"):
+					open = append(open[:len(open):len(open)], entity{tag, "</" + name + ">"})
+				}
+			}
+		case '&':
+			if j := strings.IndexByte(text[i:], ';'); j > 0 && j <= 10 && !strings.ContainsAny(text[i+1:i+j], " \n&<") {
+				end = i + j + 1
+			}
+		}
+		if end == i {
+			_, size := utf8.DecodeRuneInString(text[i:])
+			end = i + size
+		}
+		n += size(text[i:end])
+		i = end
+	}
+	return append(pts, cutPoint{len(text), n, open})
+}
+
+func tagName(tag string) string {
+	s := strings.TrimPrefix(strings.TrimPrefix(tag, "<"), "/")
+	if k := strings.IndexAny(s, " \t\n/>"); k >= 0 {
+		s = s[:k]
+	}
+	return strings.ToLower(s)
+}
+
+// popEntity removes the entity (and the ones opened after it) with the given closing markup, if it's open
+func popEntity(open []entity, close string) []entity {
+	for k := len(open) - 1; k >= 0; k-- {
+		if open[k].close == close {
+			return open[:k:k]
+		}
+	}
+	return open
+}
+
+// mdCutPoints - escape sequences and links aren't broken (Telegram Markdown or MarkdownV2)
+func mdCutPoints(text string, v2 bool, size func(string) int) []cutPoint {
+	markers := []string{"```", "`", "*", "_"}
+	if v2 {
+		markers = []string{"```", "`", "||", "__", "*", "_", "~"}
+	}
+	var pts []cutPoint
+	var open []entity
+	n := 0
+	for i := 0; i < len(text); {
+		pts = append(pts, cutPoint{i, n, open})
+		end := i
+		code := len(open) != 0 && (open[len(open)-1].close == "`" || open[len(open)-1].close == "```")
+		switch {
+		case text[i] == '\\' && i+1 < len(text) && (v2 || !code):
+			_, size := utf8.DecodeRuneInString(text[i+1:])
+			end = i + 1 + size
+		case code:
+			if c := open[len(open)-1].close; strings.HasPrefix(text[i:], c) {
+				open, end = open[:len(open)-1:len(open)-1], i+len(c)
+			}
+		case text[i] == '[':
+			end = i + mdLinkLen(text[i:], v2)
+		default:
+			for _, m := range markers {
+				if !strings.HasPrefix(text[i:], m) {
+					continue
+				}
+				end = i + len(m)
+				if popped := popEntity(open, m); len(popped) != len(open) {
+					open = popped
+				} else {
+					e := entity{m, m}
+					if m == "```" {
+						e.open = "```\n"
+					}
+					open = append(open[:len(open):len(open)], e)
+				}
+				break
+			}
+		}
+		if end == i {
+			_, size := utf8.DecodeRuneInString(text[i:])
+			end = i + size
+		}
+		n += size(text[i:end])
+		i = end
+	}
+	return append(pts, cutPoint{len(text), n, open})
+}
+
+// mdLinkLen - length of the inline link [text](url) at the start of s, 0 if it isn't the link
+func mdLinkLen(s string, v2 bool) int {
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			if v2 {
+				i++
+			}
+		case '\n':
+			return 0
+		case ']':
+			if !strings.HasPrefix(s[i:], "](") {
+				return 0
+			}
+			for j := i + 2; j < len(s); j++ {
+				switch s[j] {
+				case '\\':
+					if v2 {
+						j++
+					}
+				case ')':
+					return j + 1
+				case '\n':
+					return 0
+				}
+			}
+			return 0
+		}
+	}
+	return 0
+}
@@ -0,0 +1,122 @@
+package news
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFitMessagePlain(t *testing.T) {
+	assert := assert.New(t)
+	assert.Equal([]string{"short"}, fitMessage("short", 10, TgPlain, false, utf8.RuneCountInString))
+	// runes, not bytes
+	assert.Equal([]string{"привет мир"}, fitMessage("привет мир", 10, TgPlain, false, utf8.RuneCountInString))
+	assert.Equal([]string{"привет…"}, fitMessage("привет всем", 10, TgPlain, false, utf8.RuneCountInString))
+	// no space in the second half: hard cut
+	assert.Equal([]string{"абвгдеёжз…"}, fitMessage("абвгдеёжзийк", 10, TgPlain, false, utf8.RuneCountInString))
+
+	// line break is preferred
+	text := "first line\nsecond line\nthird"
+	assert.Equal([]string{"first line", "second line", "third"}, fitMessage(text, 15, TgPlain, true, utf8.RuneCountInString))
+	assert.Equal([]string{"first line…"}, fitMessage(text, 15, TgPlain, false, utf8.RuneCountInString))
+	for _, p := range fitMessage(strings.Repeat("ab cd ", 50), 16, TgPlain, true, utf8.RuneCountInString) {
+		assert.True(utf8.RuneCountInString(p) <= 16, p)
+	}
+}
+
+func TestFitMessageMarkup(t *testing.T) {
+	assert := assert.New(t)
+	// bold is closed and reopened
+	assert.Equal([]string{"*bold long*", "*text here*"}, fitMessage("*bold long text here*", 12, TgMarkdownV2, true, utf8.RuneCountInString))
+	assert.Equal([]string{"*bold long…*"}, fitMessage("*bold long text here*", 12, TgMarkdownV2, false, utf8.RuneCountInString))
+	// escape sequences and links aren't broken
+	assert.Equal([]string{"a\\.\\.…"}, fitMessage("a\\.\\.\\.\\.", 7, TgMarkdownV2, false, utf8.RuneCountInString))
+	parts := fitMessage("news [title](http://x/a\\)b) end", 22, TgMarkdownV2, true, utf8.RuneCountInString)
+	assert.Equal([]string{"news", "[title](http://x/a\\)b)", "end"}, parts)
+	// code content isn't markup
+	assert.Equal([]string{"`a_b c_d`", "`e_f`"}, fitMessage("`a_b c_d e_f`", 10, TgMarkdown, true, utf8.RuneCountInString))
+
+	// html tags and character references
+	assert.Equal([]string{`<a href="http://x">one</a>`, `<a href="http://x">two</a>`},
+		fitMessage(`<a href="http://x">one two</a>`, 26, TgHTML, true, utf8.RuneCountInString))
+	assert.Equal([]string{"<b>a &amp;…</b>"}, fitMessage("<b>a &amp; b &lt; c</b>", 15, TgHTML, false, utf8.RuneCountInString))
+}
+
+func TestSendParts(t *testing.T) {
+	assert := assert.New(t)
+	var sent []string
+	fail := "b"
+	send := func(s string) error {
+		if s == fail {
+			return errors.New("fail")
+		}
+		sent = append(sent, s)
+		return nil
+	}
+	waits := 0
+	ps := &partSender{wait: func() { waits++ }}
+	it := testItem("x")
+	parts := []string{"a", "b", "c"}
+	assert.Error(ps.send(it, parts, send))
+	fail = ""
+	// retry: the sent parts are skipped
+	assert.NoError(ps.send(it, parts, send))
+	assert.Equal([]string{"a", "b", "c"}, sent)
+	assert.Equal(4, waits)
+	assert.NoError(ps.send(it, parts, send))
+	assert.Len(sent, 6)
+	assert.NoError((*partSender)(nil).send(it, parts, send))
+	assert.Len(sent, 9)
+
+	info := &PubInfo{Name: "p"}
+	assert.NoError(LengthPolicy("").check(info))
+	assert.Error(LengthPolicy("wrap").check(info))
+	info.Markup = "html"
+	assert.NoError(LengthSplit.check(info))
+	assert.Equal(TgHTML, info.Markup)
+	info.Markup = "rst"
+	assert.Error(LengthSplit.check(info))
+}
+
+func TestTelegramPubSplit(t *testing.T) {
+	assert := assert.New(t)
+	api := &fakeBotAPI{}
+	pub, _ := newTestTelegramPub(t, api, TelegramPubParams{ParseMode: TgMarkdownV2})
+	assert.Equal(TelegramMaxLength, pub.MaxLength)
+	pub.MaxLength, pub.Length = 20, LengthSplit
+	it := testItem("digest")
+	it.Text = "*one two three four five*"
+	assert.NoError(pub.send(it, nil))
+	var texts []string
+	for _, c := range api.calls {
+		texts = append(texts, c.Msg["text"].(string))
+	}
+	assert.Equal([]string{"*one two three four*", "*five*"}, texts)
+}
+
+func TestHTTPPubMaxURL(t *testing.T) {
+	assert := assert.New(t)
+	var got []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = append(got, r.URL.Query().Get("text"))
+	}))
+	defer srv.Close()
+	link := srv.URL + "/send?text=%s"
+	pub := NewHTTPPub(&HTTPPubParams{
+		PubInfo: PubInfo{Name: "h", Length: LengthSplit},
+		Link:    link,
+		MaxURL:  len(link) + 30, // 32 bytes of escaped text
+	}).(*HTTPPub)
+	ch := make(chan *Item, 1)
+	it := testItem("t")
+	it.Text = "один два три"
+	ch <- it
+	close(ch)
+	pub.Publish(ch)
+	assert.Equal([]string{"один", "два", "три"}, got)
+}
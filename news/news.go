@@ -22,9 +22,29 @@ type SourceInfo struct {
 	// This field will be calculated based on Id field, if not set
 	Agency string
 	// Categories is used to "prefilter" content based on categories/tags matching
-	Categories   []string
-	Cooldown     time.Duration
-	MuteInterval DayInterval
+	Categories []string
+	Cooldown   time.Duration
+	// CooldownMin, CooldownMax - adaptive mode bounds (if CooldownMax is set):
+	// effective cooldown starts from Cooldown and is adjusted by the rate of new items and feed hints.
+	CooldownMin time.Duration
+	CooldownMax time.Duration
+	// MuteInterval - the source isn't polled within these windows
+	MuteInterval Schedule
+	// Cron - optional polling schedule, overrides cooldown
+	Cron *Cron
+	// Location - time zone of MuteInterval and Cron, nil means local time
+	Location *time.Location
+	// MaxAge - items older than that (by Published or Updated date) are dropped, 0 means no limit
+	MaxAge time.Duration
+	// NoDate - policy for items without date (keep by default)
+	NoDate DatePolicy
+	// FutureDate - policy for items dated in the future (keep by default)
+	FutureDate DatePolicy
+	// Weight - relative chance to be picked by random rotation among ready sources, 1 by default
+	Weight int
+	// Priority - ready priority source is polled before the others, i.e. at least once per its cooldown
+	// (as long as priority sources don't outnumber the ticks of their cooldown)
+	Priority bool
 }
 
 // Source - news source interface
@@ -34,6 +54,13 @@ type Source interface {
 	Receive(sink func(*Item)) error
 }
 
+// PushSource - source that delivers items by itself, it does not participate in rotation.
+// Listen should block until quit is closed, all received items should be send to sink() function.
+type PushSource interface {
+	Source
+	Listen(sink func(*Item), quit <-chan struct{}) error
+}
+
 // ItemParams - various news item params received from feed.
 type ItemParams struct {
 	Src        *SourceInfo
@@ -41,6 +68,20 @@ type ItemParams struct {
 	Link       string
 	Categories []string
 	Published  *time.Time
+	Updated    *time.Time
+	// GUID - feed entry id, if available it identifies the item (title key is still used for dedup)
+	GUID       string
+	Author     string
+	FeedTitle  string
+	Image      string // image url (item image, image enclosure or media:content)
+	Enclosures []Enclosure
+}
+
+// Enclosure - item attachment (media file)
+type Enclosure struct {
+	URL    string
+	Type   string
+	Length int64
 }
 
 // Item is "the news item" produced by Source
@@ -48,12 +89,39 @@ type Item struct {
 	ItemParams
 	words   []string // title words
 	key     DedupKey
-	DateFmt string // formated datetime (for text template use only)
+	id      DedupKey // guid key, if guid is set
+	DateFmt string   // formated datetime (for text template use only)
+	// Text - preformatted message (e.g. digest), publishers send it as is.
+	Text string
+	// Items - items of the digest
+	Items []*Item
+	// Filters - names of the filters the item matched (set by pipeline)
+	Filters []string
 }
 
 // PubInfo - publisher description
 type PubInfo struct {
 	Name string
+	// Location - time zone of the publisher (dates formatting), nil means the zone of the feed date is kept
+	Location *time.Location
+	// Retry - PublishByOne retry policy, no retries by default
+	Retry RetryPolicy
+	// DeadLetter - optional, PublishByOne stores there the items it failed to send
+	DeadLetter *DeadLetter
+	// Overflow - what to do with the item when publisher queue is full, OverflowDropNewest by default
+	Overflow OverflowPolicy
+	// SpillFile - OverflowSpill items file
+	SpillFile string
+	// Limiters - PublishByOne waits for all of them before each send attempt,
+	// a limiter may be shared by several publishers (e.g. the ones using the same bot token)
+	Limiters []*RateLimiter
+	// MaxLength - max message length (runes), 0 means no limit. Longer message is truncated or split (Length policy),
+	// markup entities of the message (Markup: TgMarkdownV2, TgMarkdown, TgHTML or plain text) aren't broken.
+	MaxLength int
+	Length    LengthPolicy
+	Markup    string
+	// Clock - time source of retry backoff, pipeline clock by default (see SetClock)
+	Clock Clock
 }
 
 // Pub aka publisher/notifier.
@@ -71,7 +139,30 @@ func (s *SourceInfo) Check() error {
 	if s.Cooldown == 0 {
 		s.Cooldown = 15 * time.Minute
 	}
-	return nil
+	if s.Weight < 0 {
+		return fmt.Errorf("source %s: negative weight", s.Name)
+	}
+	if s.Weight == 0 {
+		s.Weight = 1
+	}
+	if s.CooldownMax > 0 {
+		if s.CooldownMin == 0 {
+			s.CooldownMin = s.Cooldown / 4
+		}
+		if !(s.CooldownMin <= s.Cooldown && s.Cooldown <= s.CooldownMax) {
+			return fmt.Errorf("source %s: cooldown must be within adaptive bounds: %v..%v", s.Name, s.CooldownMin, s.CooldownMax)
+		}
+	}
+	if s.Location != nil {
+		s.MuteInterval = s.MuteInterval.In(s.Location)
+		if s.Cron != nil {
+			s.Cron = s.Cron.In(s.Location)
+		}
+	}
+	if err := s.NoDate.check(); err != nil {
+		return err
+	}
+	return s.FutureDate.check()
 }
 
 // NewItem - item constructor from params
@@ -82,17 +173,19 @@ func NewItem(p ItemParams) (*Item, error) {
 	it := &Item{ItemParams: p}
 	it.words = words.Split(it.Title)
 	it.key = StrToDedupKey(it.words...)
+	if p.GUID != "" {
+		it.id = StrToDedupKey("guid", p.GUID)
+	}
 	return it, nil
 }
 
-func (s *SourceInfo) newSink(ch chan<- *Item) func(*Item) {
-	if len(s.Categories) == 0 {
-		return func(it *Item) {
-			ch <- it
-		}
-	}
+func (s *SourceInfo) newSink(ch chan<- *Item, clock Clock) func(*Item) {
 	return func(it *Item) {
-		if !matchAnyGlobAny(it.Categories, s.Categories) {
+		if len(s.Categories) != 0 && !matchAnyGlobAny(it.Categories, s.Categories) {
+			return
+		}
+		if !s.fresh(it, clock.Now()) {
+			slog.Debugw("item_stale", "src", s.Name, "title", it.Title, "published", it.Published, "updated", it.Updated)
 			return
 		}
 		ch <- it
@@ -120,6 +213,9 @@ func (pub *logPub) Publish(ch <-chan *Item) {
 
 var URLPubPause time.Duration = time.Second //nolint:golint
 
+// HTTPPubMaxURL - default HTTPPub url length limit (common server limit is 8K of the request line)
+var HTTPPubMaxURL = 8000
+
 type ItemStringer func(it *Item) string //nolint:golint
 
 type HTTPPub struct { //nolint:golint
@@ -131,6 +227,8 @@ type HTTPPubParams struct { //nolint:golint
 	Link         string        // url sprinf format, the only argument of sprintf is item string.
 	ItemStringer               // converts item to string
 	Pause        time.Duration // pause between http queries
+	// MaxURL - max url length, longer messages are cut (see PubInfo.Length), HTTPPubMaxURL by default
+	MaxURL int
 }
 
 func NewHTTPPub(params *HTTPPubParams) Pub { //nolint:golint
@@ -141,6 +239,9 @@ func NewHTTPPub(params *HTTPPubParams) Pub { //nolint:golint
 	if p.ItemStringer == nil {
 		p.ItemStringer = itemStrDefault
 	}
+	if p.MaxURL == 0 {
+		p.MaxURL = HTTPPubMaxURL
+	}
 	return p
 }
 
@@ -152,40 +253,129 @@ func (pub *HTTPPub) Info() *PubInfo { //nolint:golint
 	return &pub.PubInfo
 }
 
-func (info *PubInfo) PublishByOne(ch <-chan *Item, delay time.Duration, publish func(*Item) error) { //nolint:golint
+func (info *PubInfo) clock() Clock {
+	if info.Clock == nil {
+		return SystemClock
+	}
+	return info.Clock
+}
 
-	for it := range ch {
-		if err := publish(it); err != nil {
-			slog.Infow("pub_error", "pub", info.Name, "err", err, "key", it.key)
+// localize returns item copy with DateFmt formatted in the publisher time zone
+// (items are shared between publishers, so they must not be modified)
+func (info *PubInfo) localize(it *Item) *Item {
+	c := *it
+	if c.Published != nil {
+		t := *c.Published
+		if info.Location != nil {
+			t = t.In(info.Location)
 		}
-		time.Sleep(delay)
+		c.DateFmt = t.Format("02.01 15:04")
 	}
+	return &c
 }
 
-func (pub *HTTPPub) Publish(ch <-chan *Item) { //nolint:golint
-	pub.PublishByOne(ch, pub.Pause, func(it *Item) error {
-		if it.Published != nil {
-			it.DateFmt = it.Published.Format("02.01 15:04")
+// render - item message: preformatted text or stringer output
+func (info *PubInfo) render(it *Item, str ItemStringer) string {
+	if it.Text != "" {
+		return it.Text
+	}
+	return str(info.localize(it))
+}
+
+// PublishByOne - publish loop, items are sent one by one at the pace of info.Limiters,
+// delay > 0 adds own limiter: one item per delay (no burst)
+func (info *PubInfo) PublishByOne(ch <-chan *Item, delay time.Duration, publish func(*Item) error) {
+	info.publishLoop(ch, delay, func(it *Item, wait func()) error {
+		wait()
+		return publish(it)
+	})
+}
+
+// publishParts - PublishByOne for the messages that may be split (see MaxLength), publish sends the parts by partSender
+func (info *PubInfo) publishParts(ch <-chan *Item, delay time.Duration, publish func(*Item, *partSender) error) {
+	ps := &partSender{}
+	info.publishLoop(ch, delay, func(it *Item, wait func()) error {
+		ps.wait, ps.waited = wait, false
+		return publish(it, ps)
+	})
+}
+
+// publishLoop - publishes items with retries, publish must call wait before each request
+func (info *PubInfo) publishLoop(ch <-chan *Item, delay time.Duration, publish func(it *Item, wait func()) error) {
+	limiters := info.Limiters
+	if delay > 0 {
+		limiters = append(limiters[:len(limiters):len(limiters)], NewRateLimiter(1, delay, 1))
+	}
+	wait := func() {
+		for _, l := range limiters {
+			l.Wait()
 		}
-		msg := pub.ItemStringer(it)
-		link := fmt.Sprintf(pub.Link, url.QueryEscape(msg))
-		r, err := pub.client.Get(link)
+	}
+	for it := range ch {
+		err := info.publishRetry(it, func(it *Item) error {
+			return publish(it, wait)
+		})
 		if err != nil {
-			return err
-		}
-		defer r.Body.Close() // nolint:errcheck
-		if r != nil {
-			st := r.StatusCode
-			if !(200 <= st && st < 300) {
-				return fmt.Errorf("bad http status: %v (%s)", st, r.Status)
+			slog.Infow("pub_error", "pub", info.Name, "err", err, "key", it.key)
+			if info.DeadLetter != nil {
+				if err := info.DeadLetter.Add(info.Name, it, err); err != nil {
+					slog.Errorw("dead_letter_error", "pub", info.Name, "err", err)
+				}
 			}
 		}
-		return nil
-	})
+	}
+}
+
+func (pub *HTTPPub) Publish(ch <-chan *Item) { //nolint:golint
+	pub.publishParts(ch, pub.Pause, pub.publish)
+}
+
+func (pub *HTTPPub) publish(it *Item, ps *partSender) error {
+	var parts []string
+	for _, msg := range pub.messages(it, pub.ItemStringer) {
+		parts = append(parts, pub.fitURL(msg)...)
+	}
+	return ps.send(it, parts, pub.send)
+}
+
+func (pub *HTTPPub) link(msg string) string {
+	return fmt.Sprintf(pub.Link, url.QueryEscape(msg))
+}
+
+// fitURL - message parts that fit into MaxURL (escaped)
+func (pub *HTTPPub) fitURL(msg string) []string {
+	max := pub.MaxURL - len(pub.link(""))
+	if max <= 0 {
+		return []string{msg}
+	}
+	return fitMessage(msg, max, pub.Markup, pub.Length == LengthSplit, func(s string) int { return len(url.QueryEscape(s)) })
+}
+
+func (pub *HTTPPub) send(msg string) error {
+	r, err := pub.client.Get(pub.link(msg))
+	if err != nil {
+		return err
+	}
+	defer r.Body.Close() // nolint:errcheck
+	if r != nil {
+		st := r.StatusCode
+		if !(200 <= st && st < 300) {
+			return &HTTPError{Code: st, Status: r.Status}
+		}
+	}
+	return nil
+}
+
+// tplFuncs - functions available in item and digest templates (besides builtin html, js, urlquery)
+var tplFuncs = template.FuncMap{
+	"md":      EscapeMarkdownV2,
+	"mdurl":   EscapeMarkdownV2URL,
+	"json":    jsonValue,
+	"jsonesc": jsonEscape,
 }
 
 func NewItemTemplateStringer(gotmpl string) ItemStringer { //nolint:golint
-	t := template.Must(template.New("item-template").Parse(gotmpl))
+	t := template.Must(template.New("item-template").Funcs(tplFuncs).Parse(gotmpl))
 	return func(it *Item) string {
 		buf := bytes.NewBuffer(make([]byte, 0, 256))
 		t.Execute(buf, it) //nolint:errcheck
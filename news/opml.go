@@ -0,0 +1,109 @@
+package news
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"io"
+	"net/url"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// OPML - subscription list document (as exported by feed readers).
+type OPML struct {
+	XMLName  xml.Name      `xml:"opml"`
+	Version  string        `xml:"version,attr"`
+	Title    string        `xml:"head>title"`
+	Outlines []OPMLOutline `xml:"body>outline"`
+}
+
+// OPMLOutline - either feed (XMLURL is set) or category (has children outlines).
+type OPMLOutline struct {
+	Text     string        `xml:"text,attr"`
+	Title    string        `xml:"title,attr,omitempty"`
+	Type     string        `xml:"type,attr,omitempty"`
+	XMLURL   string        `xml:"xmlUrl,attr,omitempty"`
+	HTMLURL  string        `xml:"htmlUrl,attr,omitempty"`
+	Outlines []OPMLOutline `xml:"outline"`
+}
+
+// ReadOPML parses OPML document
+func ReadOPML(r io.Reader) (*OPML, error) {
+	var o OPML
+	d := xml.NewDecoder(r)
+	d.Strict = false
+	if err := d.Decode(&o); err != nil {
+		return nil, err
+	}
+	return &o, nil
+}
+
+// Feeds groups feed urls by category: the text of the nearest parent outline, "" for top-level feeds.
+func (o *OPML) Feeds() map[string][]string {
+	m := make(map[string][]string)
+	var walk func(categ string, outlines []OPMLOutline)
+	walk = func(categ string, outlines []OPMLOutline) {
+		for i := range outlines {
+			ol := &outlines[i]
+			if ol.XMLURL != "" {
+				m[categ] = append(m[categ], ol.XMLURL)
+			}
+			if len(ol.Outlines) != 0 {
+				name := ol.Text
+				if name == "" {
+					name = ol.Title
+				}
+				walk(name, ol.Outlines)
+			}
+		}
+	}
+	walk("", o.Outlines)
+	return m
+}
+
+// Write writes OPML document (version 2.0)
+func (o *OPML) Write(w io.Writer) error {
+	if o.Version == "" {
+		o.Version = "2.0"
+	}
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	e := xml.NewEncoder(w)
+	e.Indent("", "  ")
+	if err := e.Encode(o); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// discoverFeed finds feed url in html page: <link rel="alternate" type="application/rss+xml" href="...">
+func discoverFeed(page string, data []byte) (string, error) {
+	base, err := url.Parse(page)
+	if err != nil {
+		return "", err
+	}
+	doc, err := goquery.NewDocumentFromReader(bytes.NewReader(data))
+	if err != nil {
+		return "", err
+	}
+	var feed string
+	doc.Find(`link[rel~="alternate"][href]`).EachWithBreak(func(_ int, s *goquery.Selection) bool {
+		typ := strings.ToLower(s.AttrOr("type", ""))
+		if !strings.Contains(typ, "rss") && !strings.Contains(typ, "atom") {
+			return true
+		}
+		if u, err := base.Parse(strings.TrimSpace(s.AttrOr("href", ""))); err == nil {
+			feed = u.String()
+			return false
+		}
+		return true
+	})
+	if feed == "" {
+		return "", errors.New("feed not found")
+	}
+	return feed, nil
+}
@@ -0,0 +1,63 @@
+package news
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const opmlDoc = `<?xml version="1.0" encoding="UTF-8"?>
+<opml version="1.0"><head><title>subs</title></head><body>
+<outline text="Top" type="rss" xmlUrl="http://top/rss"/>
+<outline text="Tech" title="Tech">
+  <outline text="A" type="rss" xmlUrl="http://a/rss" htmlUrl="http://a"/>
+  <outline text="Sub"><outline text="B" type="rss" xmlUrl="http://b/atom"/></outline>
+</outline>
+</body></opml>`
+
+func TestOPML(t *testing.T) {
+	o, err := ReadOPML(strings.NewReader(opmlDoc))
+	assert.NoError(t, err)
+	assert.Equal(t, map[string][]string{
+		"":     {"http://top/rss"},
+		"Tech": {"http://a/rss"},
+		"Sub":  {"http://b/atom"},
+	}, o.Feeds())
+
+	var buf bytes.Buffer
+	assert.NoError(t, o.Write(&buf))
+	o2, err := ReadOPML(&buf)
+	assert.NoError(t, err)
+	assert.Equal(t, "1.0", o2.Version)
+	assert.Equal(t, o.Feeds(), o2.Feeds())
+
+	buf.Reset()
+	assert.NoError(t, (&OPML{}).Write(&buf))
+	assert.Contains(t, buf.String(), `<opml version="2.0">`)
+}
+
+func TestFeedDiscovery(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`<html><head><link rel="alternate" type="text/html" href="/other">
+<link rel="alternate" type="application/rss+xml" href="/feed.xml"></head><body>site</body></html>`))
+	})
+	mux.HandleFunc("/feed.xml", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`<rss version="2.0"><channel><title>t</title><item><title>Discovered</title></item></channel></rss>`))
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	src, err := NewFeedSrc(FeedSrcParams{SourceInfo: SourceInfo{Name: "site"}, Links: []string{srv.URL + "/"}})
+	assert.NoError(t, err)
+	var items []*Item
+	assert.NoError(t, src.Receive(func(it *Item) { items = append(items, it) }))
+	if assert.Len(t, items, 1) {
+		assert.Equal(t, "Discovered", items[0].Title)
+	}
+	assert.Equal(t, []string{srv.URL + "/feed.xml"}, src.(*feedSrc).links)
+}
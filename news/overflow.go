@@ -0,0 +1,249 @@
+package news
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// OverflowPolicy - what pipeline does with the item when publisher queue is full
+type OverflowPolicy string
+
+const (
+	// OverflowDropNewest - the item is dropped (default)
+	OverflowDropNewest OverflowPolicy = "drop-newest"
+	// OverflowDropOldest - the oldest queued item is dropped to make room
+	OverflowDropOldest OverflowPolicy = "drop-oldest"
+	// OverflowBlock - pipeline waits for the room (slow publisher delays all the others)
+	OverflowBlock OverflowPolicy = "block"
+	// OverflowSpill - items are spilled to file (PubInfo.SpillFile) and queued back when there is room,
+	// spilled items survive restart
+	OverflowSpill OverflowPolicy = "spill"
+)
+
+func (p OverflowPolicy) check(info *PubInfo) error {
+	switch p {
+	case "", OverflowDropNewest, OverflowDropOldest, OverflowBlock:
+	case OverflowSpill:
+		if info.SpillFile == "" {
+			return fmt.Errorf("pub %s: spill file required", info.Name)
+		}
+	default:
+		return fmt.Errorf("pub %s: unknown overflow policy: %s", info.Name, p)
+	}
+	return nil
+}
+
+// spillRetry - how often spilled items are queued back, if there was no room
+var spillRetry = time.Second
+
+// spillQueue - items file of OverflowSpill publisher. Queued items aren't removed from the file one by one:
+// their number (offset) is kept in the side file, the items file is rewritten when most of it was queued.
+type spillQueue struct {
+	path    string
+	mu      sync.Mutex
+	pending int // spilled items that are not queued yet
+	offset  int // queued items at the head of the file
+	wake    chan struct{}
+}
+
+func newSpillQueue(path string) (*spillQueue, error) {
+	items, err := loadItems(path)
+	if err != nil {
+		return nil, err
+	}
+	q := &spillQueue{path: path, wake: make(chan struct{}, 1)}
+	if data, err := os.ReadFile(q.offsetPath()); err == nil {
+		q.offset, _ = strconv.Atoi(strings.TrimSpace(string(data)))
+	}
+	if q.offset < 0 || q.offset > len(items) {
+		q.offset = len(items)
+	}
+	q.pending = len(items) - q.offset
+	if q.pending == 0 {
+		if err := q.clear(); err != nil {
+			return nil, err
+		}
+	}
+	q.signal()
+	return q, nil
+}
+
+func (q *spillQueue) offsetPath() string {
+	return q.path + ".offset"
+}
+
+func (q *spillQueue) signal() {
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (q *spillQueue) add(it *Item) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if err := appendItems(q.path, []*Item{it}); err != nil {
+		return err
+	}
+	q.pending++
+	q.signal()
+	return nil
+}
+
+// empty - true if there is no spilled items, i.e. new item may be queued directly
+func (q *spillQueue) empty() bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.pending == 0
+}
+
+// peek - spilled items that aren't queued yet, they stay in the file until they are queued (see pop)
+func (q *spillQueue) peek() ([]*Item, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	items, err := loadItems(q.path)
+	if err != nil || q.offset > len(items) {
+		return nil, err
+	}
+	return items[q.offset:], nil
+}
+
+// pop marks the first pending item as queued, so the items that aren't queued yet survive crash or restart.
+// The offset is stored after each item, the items file is compacted once the queued items outnumber the pending ones.
+func (q *spillQueue) pop() error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.pending--
+	q.offset++
+	if q.pending == 0 {
+		return q.clear()
+	}
+	if q.offset <= q.pending {
+		return os.WriteFile(q.offsetPath(), []byte(strconv.Itoa(q.offset)), 0o644)
+	}
+	items, err := loadItems(q.path)
+	if err != nil || q.offset > len(items) {
+		return err
+	}
+	// the offset is removed first: crash in between may only repeat the queued items
+	if err := removeFile(q.offsetPath()); err != nil {
+		return err
+	}
+	if err := saveItems(q.path, items[q.offset:]); err != nil {
+		return err
+	}
+	q.offset = 0
+	return nil
+}
+
+// clear removes the files, all the items were queued
+func (q *spillQueue) clear() error {
+	if err := removeFile(q.offsetPath()); err != nil {
+		return err
+	}
+	if err := removeFile(q.path); err != nil {
+		return err
+	}
+	q.offset = 0
+	return nil
+}
+
+func removeFile(path string) error {
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// feed queues spilled items back to the publisher channel until quit is closed
+func (q *spillQueue) feed(name string, ch chan<- *Item, quit <-chan struct{}) {
+	for {
+		select {
+		case <-q.wake:
+		case <-time.After(spillRetry):
+		case <-quit:
+			return
+		}
+		items, err := q.peek()
+		if err != nil {
+			slog.Errorw("pub_spill_error", "pub", name, "err", err)
+			continue
+		}
+		for _, it := range items {
+			select {
+			case ch <- it:
+				if err := q.pop(); err != nil {
+					slog.Errorw("pub_spill_error", "pub", name, "err", err)
+				}
+			case <-quit:
+				return
+			}
+		}
+	}
+}
+
+// send queues the item according to overflow policy, false if the item was dropped
+func (p *pubData) send(it *Item, quit <-chan struct{}) bool {
+	info := p.Info()
+	switch info.Overflow {
+	case OverflowBlock:
+		select {
+		case p.ch <- it:
+			return true
+		case <-quit:
+			// pipeline is stopping: it isn't overflow, the item is dropped only if there is no room
+			select {
+			case p.ch <- it:
+				return true
+			default:
+			}
+			slog.Infow("pub_stop_drop", "pub", info.Name, "title", it.Title, "key", it.key)
+			return false
+		}
+	case OverflowDropOldest:
+		for {
+			select {
+			case p.ch <- it:
+				return true
+			default:
+			}
+			select {
+			case old := <-p.ch:
+				p.drop(old, "oldest")
+			default:
+			}
+		}
+	case OverflowSpill:
+		if p.spill.empty() {
+			select {
+			case p.ch <- it:
+				return true
+			default:
+			}
+		}
+		err := p.spill.add(it)
+		if err == nil {
+			slog.Infow("pub_spill", "pub", info.Name, "title", it.Title)
+			return true
+		}
+		slog.Errorw("pub_spill_error", "pub", info.Name, "err", err)
+	default:
+		select {
+		case p.ch <- it:
+			return true
+		default:
+		}
+	}
+	p.drop(it, "newest")
+	return false
+}
+
+func (p *pubData) drop(it *Item, which string) {
+	n := atomic.AddInt64(&p.dropped, 1)
+	slog.Infow("pub_full", "pub", p.Info().Name, "drop", which, "dropped", n, "title", it.Title, "key", it.key)
+}
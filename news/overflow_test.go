@@ -0,0 +1,225 @@
+package news
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestPubData(policy OverflowPolicy, size int) *pubData {
+	cp := newChanPub("p")
+	cp.Overflow = policy
+	return &pubData{Pub: cp, ch: make(chan *Item, size)}
+}
+
+func drain(ch chan *Item) []string {
+	var res []string
+	for {
+		select {
+		case it := <-ch:
+			res = append(res, it.Title)
+		default:
+			return res
+		}
+	}
+}
+
+func TestOverflowDrop(t *testing.T) {
+	assert := assert.New(t)
+	quit := make(chan struct{})
+	p := newTestPubData("", 2)
+	assert.True(p.send(testItem("a"), quit))
+	assert.True(p.send(testItem("b"), quit))
+	assert.False(p.send(testItem("c"), quit))
+	assert.Equal([]string{"a", "b"}, drain(p.ch))
+	assert.EqualValues(1, p.dropped)
+
+	p = newTestPubData(OverflowDropOldest, 2)
+	for _, s := range []string{"a", "b", "c", "d"} {
+		assert.True(p.send(testItem(s), quit))
+	}
+	assert.Equal([]string{"c", "d"}, drain(p.ch))
+	assert.EqualValues(2, p.dropped)
+}
+
+func TestOverflowBlock(t *testing.T) {
+	assert := assert.New(t)
+	quit := make(chan struct{})
+	p := newTestPubData(OverflowBlock, 1)
+	assert.True(p.send(testItem("a"), quit))
+	done := make(chan bool)
+	go func() { done <- p.send(testItem("b"), quit) }()
+	select {
+	case <-done:
+		t.Fatal("send must block")
+	case <-time.After(20 * time.Millisecond):
+	}
+	assert.Equal("a", (<-p.ch).Title)
+	assert.True(<-done)
+	assert.Equal("b", (<-p.ch).Title)
+
+	assert.True(p.send(testItem("c"), quit))
+	go func() { done <- p.send(testItem("d"), quit) }()
+	close(quit)
+	// stop isn't overflow: the item is dropped, but not counted
+	assert.False(<-done)
+	assert.EqualValues(0, p.dropped)
+	assert.Equal("c", (<-p.ch).Title)
+	assert.True(p.send(testItem("e"), quit))
+	assert.Equal("e", (<-p.ch).Title)
+}
+
+func TestOverflowSpill(t *testing.T) {
+	assert := assert.New(t)
+	dir, err := os.MkdirTemp("", "spill")
+	assert.NoError(err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "spill")
+	quit := make(chan struct{})
+
+	p := newTestPubData(OverflowSpill, 1)
+	p.spill, err = newSpillQueue(path)
+	assert.NoError(err)
+	for _, s := range []string{"a", "b", "c"} {
+		assert.True(p.send(testItem(s), quit))
+	}
+	assert.Equal([]string{"a"}, drain(p.ch))
+	// the room is available, but the spilled items go first
+	assert.True(p.send(testItem("d"), quit))
+	assert.Empty(drain(p.ch))
+	assert.EqualValues(0, p.dropped)
+
+	// restart: spilled items are restored and fed back in order
+	p = newTestPubData(OverflowSpill, 1)
+	p.spill, err = newSpillQueue(path)
+	assert.NoError(err)
+	assert.False(p.spill.empty())
+	done := make(chan struct{})
+	go func() {
+		p.spill.feed("p", p.ch, quit)
+		close(done)
+	}()
+	var got []string
+	for len(got) < 3 {
+		select {
+		case it := <-p.ch:
+			got = append(got, it.Title)
+		case <-time.After(time.Second):
+			t.Fatalf("spilled items: %v", got)
+		}
+	}
+	assert.Equal([]string{"b", "c", "d"}, got)
+	close(quit)
+	<-done
+	assert.True(p.spill.empty())
+	_, err = os.Stat(path)
+	assert.True(os.IsNotExist(err))
+}
+
+func TestOverflowSpillCrash(t *testing.T) {
+	assert := assert.New(t)
+	dir, err := os.MkdirTemp("", "spill")
+	assert.NoError(err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "spill")
+
+	q, err := newSpillQueue(path)
+	assert.NoError(err)
+	for _, s := range []string{"a", "b", "c"} {
+		assert.NoError(q.add(testItem(s)))
+	}
+	// feed is blocked on the full channel, the process is killed (feed is never stopped)
+	ch := make(chan *Item, 1)
+	go q.feed("p", ch, make(chan struct{}))
+	assert.Equal("a", recvItem(t, ch).Title)
+	var items []*Item
+	for end := time.Now().Add(time.Second); time.Now().Before(end); time.Sleep(time.Millisecond) {
+		if items, err = loadItems(path); err == nil && len(items) == 1 {
+			break
+		}
+	}
+	// b is queued, c is not: it's still in the file
+	if assert.Len(items, 1) {
+		assert.Equal("c", items[0].Title)
+	}
+}
+
+func TestOverflowSpillOffset(t *testing.T) {
+	assert := assert.New(t)
+	dir, err := os.MkdirTemp("", "spill")
+	assert.NoError(err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "spill")
+
+	q, err := newSpillQueue(path)
+	assert.NoError(err)
+	for _, s := range []string{"a", "b", "c", "d", "e"} {
+		assert.NoError(q.add(testItem(s)))
+	}
+	assert.NoError(q.pop())
+	assert.NoError(q.pop())
+	// the items file isn't rewritten, only the offset is stored
+	items, err := loadItems(path)
+	assert.NoError(err)
+	assert.Len(items, 5)
+
+	// restart: the queued items are skipped
+	q, err = newSpillQueue(path)
+	assert.NoError(err)
+	assert.Equal(3, q.pending)
+	items, err = q.peek()
+	assert.NoError(err)
+	if assert.Len(items, 3) {
+		assert.Equal("c", items[0].Title)
+	}
+	// most of the file was queued: it's compacted
+	assert.NoError(q.pop())
+	items, err = loadItems(path)
+	assert.NoError(err)
+	assert.Len(items, 2)
+	_, err = os.Stat(q.offsetPath())
+	assert.True(os.IsNotExist(err))
+	assert.NoError(q.pop())
+	assert.NoError(q.pop())
+	assert.True(q.empty())
+	_, err = os.Stat(path)
+	assert.True(os.IsNotExist(err))
+}
+
+func TestOverflowSpillQuit(t *testing.T) {
+	assert := assert.New(t)
+	dir, err := os.MkdirTemp("", "spill")
+	assert.NoError(err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "spill")
+
+	q, err := newSpillQueue(path)
+	assert.NoError(err)
+	for _, s := range []string{"a", "b", "c"} {
+		assert.NoError(q.add(testItem(s)))
+	}
+	// publisher channel is full: items stay in the file on quit
+	ch := make(chan *Item)
+	quit := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		q.feed("p", ch, quit)
+		close(done)
+	}()
+	time.Sleep(20 * time.Millisecond)
+	close(quit)
+	<-done
+	items, err := loadItems(path)
+	assert.NoError(err)
+	assert.Len(items, 3)
+
+	var info PubInfo
+	info.Name = "p"
+	assert.Error(OverflowSpill.check(&info))
+	assert.Error(OverflowPolicy("sometimes").check(&info))
+	info.SpillFile = path
+	assert.NoError(OverflowSpill.check(&info))
+}
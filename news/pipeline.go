@@ -4,13 +4,14 @@ import (
 	"errors"
 	"fmt"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	log "github.com/sirupsen/logrus"
 )
 
 // Pipeline - news filtering pipeline
-// Pipeline uses rotator, that schedules source requests randomly,
+// Pipeline uses rotator, that schedules source requests randomly (see SetRotation),
 // so use rand.Seed() before launching pipeline.
 type Pipeline struct {
 	sources map[string]*srcData
@@ -18,6 +19,8 @@ type Pipeline struct {
 	filters []*Filter
 	dedup   Deduplicator // deduplicator (LRU) for news titles (to avoid repeated notifications)
 	rot     rotator
+	clock   Clock
+	rand    Rand
 
 	chanSize int
 	quit     chan struct{} // stop channel
@@ -25,19 +28,54 @@ type Pipeline struct {
 
 	lock    sync.Mutex // guards modification and launch
 	started bool
+	running bool           // sources are ready to be fetched
 	wg      sync.WaitGroup // tracks launched goroutines
+	feeders sync.WaitGroup // spill feeders, they are stopped before publisher channels are closed
+
+	listeners sync.WaitGroup // push sources, prodc is closed after they return
+	sinkLock  sync.RWMutex   // sinks hold read lock while writing to prodc
+	stopped   bool           // prodc is closed, guarded by sinkLock
 }
 
 type srcData struct {
 	Source
 	quit      chan struct{}
 	filterInd []int
-	guard     Guard // guards rotation (i.e. only 1 goroutine may read source)
+	guard     Guard             // guards rotation (i.e. only 1 goroutine may read source)
+	adapt     *adaptiveCooldown // nil if source cooldown isn't adaptive
+	sink      func(*Item)
+	elem      int // rotator element index, -1 for push sources
+}
+
+// receive - calls Receive, in adaptive mode updates cooldown
+func (s *srcData) receive(sink func(*Item)) {
+	newItems := 0
+	if s.adapt != nil {
+		sink = s.adapt.countNew(sink, &newItems)
+	}
+	if err := s.Receive(sink); err != nil {
+		log.WithField("src", s.Info().Name).Error(err)
+	}
+	if s.adapt != nil {
+		var hint time.Duration
+		if h, ok := s.Source.(PollHinter); ok {
+			hint = h.PollHint()
+		}
+		s.adapt.update(newItems, hint)
+	}
+}
+
+// sinkHolder - source that delivers items outside of Receive (e.g. WebSub content),
+// pipeline gives it the sink before the start
+type sinkHolder interface {
+	setSink(sink func(*Item))
 }
 
 type pubData struct {
 	Pub
-	ch chan *Item
+	ch      chan *Item
+	spill   *spillQueue // OverflowSpill mode only
+	dropped int64       // atomic, number of items dropped due to overflow
 }
 
 // NewPipeline - creates pipeline
@@ -48,6 +86,8 @@ func NewPipeline(chanSize int, d Deduplicator) *Pipeline {
 		sources:  make(map[string]*srcData),
 		pubs:     make(map[string]*pubData),
 		chanSize: chanSize,
+		clock:    SystemClock,
+		rand:     SystemRand,
 	}
 }
 
@@ -81,7 +121,22 @@ func (pl *Pipeline) AddPublisher(p Pub) error { //nolint:golint
 		if _, has := pl.pubs[n]; has {
 			return fmt.Errorf("duplicate publisher: %s", n)
 		}
-		pl.pubs[n] = &pubData{Pub: p}
+		info := p.Info()
+		if err := info.Overflow.check(info); err != nil {
+			return err
+		}
+		if err := info.Length.check(info); err != nil {
+			return err
+		}
+		pd := &pubData{Pub: p}
+		if info.Overflow == OverflowSpill {
+			spill, err := newSpillQueue(info.SpillFile)
+			if err != nil {
+				return fmt.Errorf("pub %s: %s", n, err)
+			}
+			pd.spill = spill
+		}
+		pl.pubs[n] = pd
 		return nil
 	})
 }
@@ -96,6 +151,28 @@ func (pl *Pipeline) AddFilter(f *Filter) error { //nolint:golint
 	})
 }
 
+// SetRotation - sets source scheduling params (random single source per tick by default)
+func (pl *Pipeline) SetRotation(p RotationParams) error {
+	return pl.modify(func() error {
+		if err := p.Check(); err != nil {
+			return err
+		}
+		pl.rot.RotationParams = p
+		return nil
+	})
+}
+
+// SetClock - replaces time and random sources of the scheduling (for testing)
+func (pl *Pipeline) SetClock(clock Clock, rnd Rand) error {
+	return pl.modify(func() error {
+		if clock == nil || rnd == nil {
+			return errors.New("pipeline: nil clock or rand")
+		}
+		pl.clock, pl.rand = clock, rnd
+		return nil
+	})
+}
+
 func (pl *Pipeline) beforeStart() error {
 	pl.lock.Lock()
 	defer pl.lock.Unlock()
@@ -148,46 +225,119 @@ func (pl *Pipeline) Run() error {
 	}
 	// create producer channel: channel to which the sources write
 	pl.prodc = make(chan *Item, 2*pl.chanSize)
+	pl.quit = make(chan struct{})
 	pl.wg.Add(len(pl.pubs))
 	for _, p := range pl.pubs {
 		// create each publisher channel: channel that a pub-r reads.
 		p.ch = make(chan *Item, pl.chanSize)
+		if info := p.Info(); info.Clock == nil {
+			info.Clock = pl.clock
+		}
 		pub := p
 		go func() {
 			defer pl.wg.Done()
 			pub.Publish(pub.ch)
 		}()
+		if pub.spill != nil {
+			GoWG(&pl.feeders, func() {
+				pub.spill.feed(pub.Info().Name, pub.ch, pl.quit)
+			})
+		}
 	}
 
 	for _, _s := range pl.sources {
 		s, info := _s, _s.Info()
-		sink := info.newSink(pl.prodc)
+		sink := pl.guardSink(info.newSink(pl.prodc, pl.clock))
+		s.sink, s.elem = sink, -1
+		if h, ok := s.Source.(sinkHolder); ok {
+			h.setSink(sink)
+		}
+		if ps, ok := s.Source.(PushSource); ok {
+			pl.listeners.Add(1)
+			GoWG(&pl.wg, func() {
+				defer pl.listeners.Done()
+				if err := ps.Listen(sink, pl.quit); err != nil {
+					log.WithField("src", info.Name).Error(err)
+				}
+			})
+			continue
+		}
 		// add rotator element for each source
-		pl.rot.Elems = append(pl.rot.Elems, rotatorElem{
+		elem := rotatorElem{
 			Cooldown: info.Cooldown,
+			Weight:   info.Weight,
+			Priority: info.Priority,
 			Fn: func(now time.Time) {
 				if info.MuteInterval.ContainsTime(now) {
 					return
 				}
-				s.guard.Go(func() {
-					if err := s.Receive(sink); err != nil {
-						log.WithField("src", info.Name).Error(err)
-					}
-				})
+				if !s.guard.CanLock() {
+					return
+				}
+				defer s.guard.Unlock()
+				s.receive(sink)
 			},
+		}
+		if info.Cron != nil {
+			elem.Ready = info.Cron.Due
+		} else if s.adapt = newAdaptiveCooldown(info); s.adapt != nil {
+			elem.CooldownFn = s.adapt.get
+		}
+		s.elem = len(pl.rot.Elems)
+		pl.rot.Elems = append(pl.rot.Elems, elem)
+	}
+	pl.rot.clock, pl.rot.rand = pl.clock, pl.rand
+	if len(pl.rot.Elems) != 0 {
+		GoWG(&pl.wg, func() {
+			pl.rot.run(pl.quit)
 		})
 	}
-	pl.quit = make(chan struct{})
-	GoWG(&pl.wg, func() {
-		pl.rot.run(pl.quit)
-	})
 	GoWG(&pl.wg, pl.run)
+	pl.lock.Lock()
+	pl.running = true
+	pl.lock.Unlock()
 	return nil
 }
 
-func (pl *Pipeline) run() {
+var (
+	// ErrSourceBusy - the source is being received right now
+	ErrSourceBusy = errors.New("source is busy")
+	// ErrUnknownSource - there is no source with such name
+	ErrUnknownSource = errors.New("unknown source")
+)
 
-	pubs := make(map[string]struct{})
+// Fetch - receives the named source immediately (mute windows are ignored) and resets its cooldown.
+// Fetch blocks until Receive returns, ErrSourceBusy is returned if the source is being received already.
+func (pl *Pipeline) Fetch(name string) error {
+	pl.lock.Lock()
+	running := pl.running
+	pl.lock.Unlock()
+	if !running {
+		return errors.New("pipeline: not running")
+	}
+	select {
+	case <-pl.quit:
+		return errors.New("pipeline: stopped")
+	default:
+	}
+	s, ok := pl.sources[name]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrUnknownSource, name)
+	}
+	if s.elem < 0 {
+		return fmt.Errorf("source %s: push source can't be fetched", name)
+	}
+	if !s.guard.CanLock() {
+		return ErrSourceBusy
+	}
+	defer s.guard.Unlock()
+	slog.Infow("src_fetch", "src", name)
+	pl.rot.touch(s.elem, pl.clock.Now())
+	s.receive(s.sink)
+	return nil
+}
+
+func (pl *Pipeline) run() {
 
 	for it := range pl.prodc {
 		_, ok := pl.sources[it.Src.Name]
@@ -196,33 +346,61 @@ func (pl *Pipeline) run() {
 			continue
 		}
 
-		atLeastOneMatch := false
+		// pub name -> names of the matched filters routed to that pub
+		pubs := make(map[string][]string)
 		for _, f := range pl.filters {
 			if f.dnf.MatchWords(it.words) {
-				atLeastOneMatch = true
 				for _, pname := range f.pubs {
-					pubs[pname] = struct{}{}
+					pubs[pname] = append(pubs[pname], f.Name)
 				}
 			}
 		}
 
-		if !atLeastOneMatch {
+		if len(pubs) == 0 {
 			continue
 		}
 
-		if !pl.dedup.Keep(it.key) {
+		// both title and guid keys are recorded, item is duplicate if any of them was seen
+		keep := pl.dedup.Keep(it.key)
+		if it.GUID != "" && !pl.dedup.Keep(it.id) {
+			keep = false
+		}
+		if !keep {
 			continue
 		}
 
-		for pname := range pubs {
-			logEvent := "pub_send"
-			select {
-			case pl.pubs[pname].ch <- it:
-			default:
-				logEvent = "pub_full"
+		for pname, filters := range pubs {
+			// items are shared between publishers, each one gets own copy with its filters
+			c := *it
+			c.Filters = filters
+			if pl.pubs[pname].send(&c, pl.quit) {
+				slog.Infow("pub_send", "pub", pname, "title", it.Title, "link", it.Link, "src", it.Src.Name, "key", it.key)
 			}
-			slog.Infow(logEvent, "pub", pname, "title", it.Title, "link", it.Link, "src", it.Src.Name, "key", it.key)
-			delete(pubs, pname)
+		}
+	}
+	pl.feeders.Wait()
+	for _, p := range pl.pubs {
+		close(p.ch)
+	}
+}
+
+// Dropped - number of items dropped by each publisher due to queue overflow
+func (pl *Pipeline) Dropped() map[string]int64 {
+	m := make(map[string]int64, len(pl.pubs))
+	for n, p := range pl.pubs {
+		m[n] = atomic.LoadInt64(&p.dropped)
+	}
+	return m
+}
+
+// guardSink - sink that drops items once the pipeline is stopped,
+// e.g. the ones of http handlers or Fetch that are still running
+func (pl *Pipeline) guardSink(sink func(*Item)) func(*Item) {
+	return func(it *Item) {
+		pl.sinkLock.RLock()
+		defer pl.sinkLock.RUnlock()
+		if !pl.stopped {
+			sink(it)
 		}
 	}
 }
@@ -230,11 +408,13 @@ func (pl *Pipeline) run() {
 //Stop - stops pipeline
 //todo check & refac.
 func (pl *Pipeline) Stop() {
-	pl.quit <- struct{}{}
+	close(pl.quit)
+	// prodc is drained by run until it's closed, so the sinks in progress aren't blocked
+	pl.listeners.Wait()
+	pl.sinkLock.Lock()
+	pl.stopped = true
 	close(pl.prodc)
-	for _, p := range pl.pubs {
-		close(p.ch)
-	}
+	pl.sinkLock.Unlock()
 }
 
 // todo refac/remove
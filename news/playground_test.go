@@ -70,7 +70,7 @@ func TestRotator(t *testing.T) {
 	logger := zap.NewExample()
 	SetLogger(logger)
 	rot := rotator{
-		Tick: time.Second * 1,
+		RotationParams: RotationParams{Tick: time.Second * 1},
 		Elems: []rotatorElem{
 			rotatorElem{
 				Cooldown: time.Second * 2,
@@ -102,7 +102,7 @@ func TestRotator(t *testing.T) {
 }
 
 func TestMute(t *testing.T) {
-	di := DayInterval{}
+	di := Schedule{}
 	now := time.Now()
 	di = DayHoursFromTo(20, 4)
 	fmt.Printf("!!!%v %s!!!!!!", di.ContainsTime(now), di)
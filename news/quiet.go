@@ -0,0 +1,116 @@
+package news
+
+import (
+	"errors"
+	"time"
+)
+
+// QuietPubParams - publisher quiet hours params
+type QuietPubParams struct {
+	Quiet Schedule // items are queued within these windows
+	// Digest - queued items are delivered as one message (rendered by DigestStringer)
+	Digest         bool
+	DigestStringer DigestStringer
+	// QueueFile - optional, queue is persisted there (json lines) and restored on start
+	QueueFile string
+	// Check - how often the end of quiet window is checked, 1m by default
+	Check time.Duration
+	// Clock - optional, SystemClock by default
+	Clock Clock
+}
+
+type quietPub struct {
+	Pub
+	QuietPubParams
+	queue []*Item
+}
+
+// NewQuietPub wraps publisher: within quiet windows matched items are queued, and delivered when the window ends.
+func NewQuietPub(pub Pub, p QuietPubParams) (Pub, error) {
+	if p.Quiet.IsEmpty() {
+		return nil, errors.New("quiet pub: quiet windows required")
+	}
+	if p.Digest && p.DigestStringer == nil {
+		p.DigestStringer = NewDigestTemplateStringer(DigestTemplateDefault, pub.Info())
+	}
+	if p.Check == 0 {
+		p.Check = time.Minute
+	}
+	if p.Clock == nil {
+		p.Clock = SystemClock
+	}
+	q := &quietPub{Pub: pub, QuietPubParams: p}
+	if p.QueueFile != "" {
+		items, err := loadItems(p.QueueFile)
+		if err != nil {
+			return nil, err
+		}
+		q.queue = items
+	}
+	return q, nil
+}
+
+func (q *quietPub) Publish(in <-chan *Item) {
+	out := make(chan *Item, cap(in))
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		q.Pub.Publish(out)
+	}()
+	defer func() {
+		close(out)
+		<-done
+	}()
+	tick := time.NewTicker(q.Check)
+	defer tick.Stop()
+	q.flush(out)
+	for {
+		select {
+		case it, ok := <-in:
+			if !ok {
+				q.flush(out)
+				return
+			}
+			if q.Quiet.ContainsTime(q.Clock.Now()) {
+				q.enqueue(it)
+				continue
+			}
+			q.flush(out)
+			out <- it
+		case <-tick.C:
+			q.flush(out)
+		}
+	}
+}
+
+func (q *quietPub) enqueue(it *Item) {
+	q.queue = append(q.queue, it)
+	slog.Infow("pub_quiet_queue", "pub", q.Info().Name, "title", it.Title, "queued", len(q.queue))
+	q.save()
+}
+
+// flush delivers queued items if quiet window has ended
+func (q *quietPub) flush(out chan<- *Item) {
+	if len(q.queue) == 0 || q.Quiet.ContainsTime(q.Clock.Now()) {
+		return
+	}
+	slog.Infow("pub_quiet_flush", "pub", q.Info().Name, "count", len(q.queue), "digest", q.Digest)
+	if q.Digest {
+		out <- newDigestItem(q.DigestStringer(q.queue), q.queue)
+	} else {
+		for _, it := range q.queue {
+			out <- it
+		}
+	}
+	q.queue = nil
+	q.save()
+}
+
+func (q *quietPub) save() {
+	if q.QueueFile == "" {
+		return
+	}
+	if err := saveItems(q.QueueFile, q.queue); err != nil {
+		slog.Errorw("pub_quiet_save_error", "pub", q.Info().Name, "err", err)
+	}
+}
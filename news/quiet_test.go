@@ -0,0 +1,96 @@
+package news
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// chanPub - test publisher, sends received items to the channel
+type chanPub struct {
+	PubInfo
+	out chan *Item
+}
+
+func newChanPub(name string) *chanPub {
+	return &chanPub{PubInfo{Name: name}, make(chan *Item, 100)}
+}
+
+func (p *chanPub) Info() *PubInfo { return &p.PubInfo }
+
+func (p *chanPub) Publish(in <-chan *Item) {
+	for it := range in {
+		p.out <- it
+	}
+	close(p.out)
+}
+
+func testItem(title string) *Item {
+	it, err := NewItem(ItemParams{Src: &SourceInfo{Name: "src"}, Title: title, Link: "http://x/" + title})
+	if err != nil {
+		panic(err)
+	}
+	return it
+}
+
+func TestQuietPub(t *testing.T) {
+	dir, err := os.MkdirTemp("", "quiet")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	queue := filepath.Join(dir, "queue")
+
+	quiet, _ := ParseSchedule("23:00-08:00")
+	clock := newFakeClock(time.Date(2018, 3, 5, 23, 30, 0, 0, time.UTC))
+
+	newPub := func(digest bool) (*chanPub, chan *Item) {
+		cp := newChanPub("q")
+		p, err := NewQuietPub(cp, QuietPubParams{Quiet: quiet, Digest: digest, QueueFile: queue, Check: 5 * time.Millisecond, Clock: clock})
+		assert.NoError(t, err)
+		in := make(chan *Item, 10)
+		go p.Publish(in)
+		return cp, in
+	}
+
+	cp, in := newPub(false)
+	in <- testItem("one")
+	in <- testItem("two")
+	close(in) // "restart" within quiet window: queue is persisted
+	_, ok := <-cp.out
+	assert.False(t, ok)
+	data, err := os.ReadFile(queue)
+	assert.NoError(t, err)
+	assert.Contains(t, string(data), `"src":"src"`)
+
+	cp, in = newPub(true)
+	in <- testItem("three")
+	time.Sleep(20 * time.Millisecond)
+	assert.Len(t, cp.out, 0)
+	clock.Set(time.Date(2018, 3, 6, 8, 0, 0, 0, time.UTC))
+	select {
+	case d := <-cp.out:
+		assert.Len(t, d.Items, 3)
+		assert.Equal(t, "3 news:\n• one http://x/one\n• two http://x/two\n• three http://x/three\n", d.Text)
+	case <-time.After(time.Second):
+		t.Fatal("digest not delivered")
+	}
+	_, err = os.Stat(queue)
+	assert.True(t, os.IsNotExist(err), "queue file removed")
+
+	in <- testItem("four")
+	assert.Equal(t, "four", (<-cp.out).Title)
+	close(in)
+}
+
+func TestDigestBySource(t *testing.T) {
+	a, b := &SourceInfo{Name: "a"}, &SourceInfo{Name: "b"}
+	items := []*Item{
+		{ItemParams: ItemParams{Src: a, Title: "1"}},
+		{ItemParams: ItemParams{Src: b, Title: "2"}},
+		{ItemParams: ItemParams{Src: a, Title: "3"}},
+	}
+	s := NewDigestTemplateStringer("{{range .BySource}}{{.Name}}:{{range .Items}} {{.Title}}{{end}};{{end}}", &PubInfo{})
+	assert.Equal(t, "a: 1 3;b: 2;", s(items))
+}
@@ -0,0 +1,84 @@
+package news
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RateLimiter - token bucket: N tokens per period, up to Burst tokens are accumulated.
+// It's safe for concurrent use, so it may be shared by publishers (e.g. the same bot token).
+type RateLimiter struct {
+	mu     sync.Mutex
+	rate   float64 // tokens per nanosecond
+	burst  float64
+	tokens float64
+	last   time.Time
+	clock  Clock
+}
+
+// NewRateLimiter creates limiter of n events per period with the given burst (at least 1), the bucket is full initially.
+func NewRateLimiter(n int, per time.Duration, burst int) *RateLimiter {
+	if n <= 0 || per <= 0 {
+		panic("rate limiter: positive rate required")
+	}
+	if burst < 1 {
+		burst = 1
+	}
+	return &RateLimiter{
+		rate:   float64(n) / float64(per),
+		burst:  float64(burst),
+		tokens: float64(burst),
+		clock:  SystemClock,
+	}
+}
+
+// Wait blocks until the token is available
+func (l *RateLimiter) Wait() {
+	if d := l.reserve(); d > 0 {
+		<-l.clock.After(d)
+	}
+}
+
+// reserve takes the token and returns the time to wait for it (tokens may go negative: waiters are queued)
+func (l *RateLimiter) reserve() time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := l.clock.Now()
+	if !l.last.IsZero() {
+		l.tokens += float64(now.Sub(l.last)) * l.rate
+		if l.tokens > l.burst {
+			l.tokens = l.burst
+		}
+	}
+	l.last = now
+	l.tokens--
+	if l.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(math.Ceil(-l.tokens / l.rate))
+}
+
+// ParseRate parses "N/period" rate: "20/1m", "1/3s", "30/s" (period number may be omitted)
+func ParseRate(s string) (int, time.Duration, error) {
+	parts := strings.SplitN(s, "/", 2)
+	if len(parts) != 2 {
+		return 0, 0, fmt.Errorf("rate %q: N/period expected", s)
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(parts[0]))
+	if err != nil || n <= 0 {
+		return 0, 0, fmt.Errorf("rate %q: bad number", s)
+	}
+	p := strings.TrimSpace(parts[1])
+	if p != "" && (p[0] < '0' || p[0] > '9') {
+		p = "1" + p
+	}
+	per, err := time.ParseDuration(p)
+	if err != nil || per <= 0 {
+		return 0, 0, fmt.Errorf("rate %q: bad period", s)
+	}
+	return n, per, nil
+}
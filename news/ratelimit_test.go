@@ -0,0 +1,78 @@
+package news
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRateLimiterReserve(t *testing.T) {
+	assert := assert.New(t)
+	clock := newFakeClock(time.Date(2018, 3, 5, 10, 0, 0, 0, time.UTC))
+	l := NewRateLimiter(2, time.Second, 3)
+	l.clock = clock
+
+	// burst
+	for i := 0; i < 3; i++ {
+		assert.Zero(l.reserve())
+	}
+	// waiters are queued
+	assert.Equal(500*time.Millisecond, l.reserve())
+	assert.Equal(time.Second, l.reserve())
+
+	// the bucket is refilled up to burst
+	clock.Advance(time.Hour)
+	for i := 0; i < 3; i++ {
+		assert.Zero(l.reserve())
+	}
+	clock.Advance(time.Second)
+	assert.Zero(l.reserve())
+	assert.Zero(l.reserve())
+	assert.Equal(500*time.Millisecond, l.reserve())
+}
+
+func TestRateLimiterShared(t *testing.T) {
+	assert := assert.New(t)
+	clock := newFakeClock(time.Date(2018, 3, 5, 10, 0, 0, 0, time.UTC))
+	shared := NewRateLimiter(1, time.Minute, 1)
+	shared.clock = clock
+	var sent []string
+	sink := make(chan string, 10)
+	for _, name := range []string{"a", "b"} {
+		name := name
+		info := &PubInfo{Name: name, Limiters: []*RateLimiter{shared}}
+		ch := make(chan *Item, 1)
+		ch <- testItem(name)
+		close(ch)
+		go info.PublishByOne(ch, 0, func(it *Item) error {
+			sink <- it.Title
+			return nil
+		})
+	}
+	sent = append(sent, <-sink)
+	clock.waitAfter(t, 1)
+	assert.Len(sink, 0)
+	clock.Advance(time.Minute)
+	sent = append(sent, <-sink)
+	assert.ElementsMatch([]string{"a", "b"}, sent)
+}
+
+func TestParseRate(t *testing.T) {
+	assert := assert.New(t)
+	for s, want := range map[string][2]int64{
+		"20/1m": {20, int64(time.Minute)},
+		"1/3s":  {1, int64(3 * time.Second)},
+		"30/s":  {30, int64(time.Second)},
+		" 5/h ": {5, int64(time.Hour)},
+	} {
+		n, per, err := ParseRate(s)
+		if assert.NoError(err, s) {
+			assert.Equal(want, [2]int64{int64(n), int64(per)}, s)
+		}
+	}
+	for _, s := range []string{"", "20", "0/s", "x/s", "1/", "1/0s", "1/ages"} {
+		_, _, err := ParseRate(s)
+		assert.Error(err, s)
+	}
+}
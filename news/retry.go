@@ -0,0 +1,90 @@
+package news
+
+import (
+	"errors"
+	"net"
+	"net/textproto"
+	"net/url"
+	"time"
+)
+
+// RetryableDefault - http statuses that are retried by default
+var RetryableDefault = []int{408, 425, 429, 500, 502, 503, 504}
+
+// RetryPolicy - publisher retry policy. Zero value means no retries.
+type RetryPolicy struct {
+	MaxAttempts int           // max number of attempts to send the item, 1 (no retries) by default
+	Backoff     time.Duration // pause before the first retry, doubled on each retry, 1s by default
+	MaxBackoff  time.Duration // 5m by default
+	// Retryable - http statuses to retry, RetryableDefault if empty.
+	// Network errors and smtp transient (4xx) errors are always retried.
+	Retryable []int
+}
+
+// StatusError - error caused by http status (or Bot API error code)
+type StatusError interface {
+	error
+	StatusCode() int
+}
+
+func (e *HTTPError) StatusCode() int { return e.Code } //nolint:golint
+
+func (e *TelegramError) StatusCode() int { return e.Code } //nolint:golint
+
+func (p *RetryPolicy) retryable(err error) bool {
+	var te *textproto.Error
+	if errors.As(err, &te) {
+		return 400 <= te.Code && te.Code < 500 // smtp transient failure
+	}
+	var se StatusError
+	if errors.As(err, &se) {
+		codes := p.Retryable
+		if len(codes) == 0 {
+			codes = RetryableDefault
+		}
+		for _, c := range codes {
+			if c == se.StatusCode() {
+				return true
+			}
+		}
+		return false
+	}
+	var ue *url.Error
+	var ne net.Error
+	return errors.As(err, &ue) || errors.As(err, &ne)
+}
+
+// backoff - pause before the retry (attempt >= 1), Bot API retry_after is honoured
+func (p *RetryPolicy) backoff(attempt int, err error) time.Duration {
+	d, max := p.Backoff, p.MaxBackoff
+	if d <= 0 {
+		d = time.Second
+	}
+	if max <= 0 {
+		max = 5 * time.Minute
+	}
+	for i := 1; i < attempt && d < max; i++ {
+		d *= 2
+	}
+	if d > max {
+		d = max
+	}
+	var te *TelegramError
+	if errors.As(err, &te) && te.RetryAfter > d {
+		d = te.RetryAfter
+	}
+	return d
+}
+
+// publishRetry - publishes the item according to retry policy
+func (info *PubInfo) publishRetry(it *Item, publish func(*Item) error) error {
+	for attempt := 1; ; attempt++ {
+		err := publish(it)
+		if err == nil || attempt >= info.Retry.MaxAttempts || !info.Retry.retryable(err) {
+			return err
+		}
+		d := info.Retry.backoff(attempt, err)
+		slog.Infow("pub_retry", "pub", info.Name, "err", err, "attempt", attempt, "pause", d)
+		<-info.clock().After(d)
+	}
+}
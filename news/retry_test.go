@@ -0,0 +1,140 @@
+package news
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRetryPolicy(t *testing.T) {
+	assert := assert.New(t)
+	p := RetryPolicy{Backoff: time.Second, MaxBackoff: 5 * time.Second}
+	var got []time.Duration
+	for attempt := 1; attempt <= 4; attempt++ {
+		got = append(got, p.backoff(attempt, errors.New("x")))
+	}
+	assert.Equal([]time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second}, got)
+	assert.Equal(30*time.Second, p.backoff(1, &TelegramError{Code: 429, RetryAfter: 30 * time.Second}))
+	assert.Equal(time.Second, (&RetryPolicy{}).backoff(1, nil))
+
+	assert.True(p.retryable(&HTTPError{Code: 503}))
+	assert.False(p.retryable(&HTTPError{Code: 400}))
+	assert.True(p.retryable(&url.Error{Op: "Post", URL: "http://x", Err: errors.New("connection refused")}))
+	assert.False(p.retryable(errors.New("template error")))
+	p.Retryable = []int{400}
+	assert.True(p.retryable(&TelegramError{Code: 400}))
+	assert.False(p.retryable(&TelegramError{Code: 503}))
+}
+
+// sleepClock - records After durations, the channels fire immediately
+type sleepClock struct {
+	sleeps []time.Duration
+}
+
+func (c *sleepClock) Now() time.Time { return time.Now() }
+
+func (c *sleepClock) After(d time.Duration) <-chan time.Time {
+	c.sleeps = append(c.sleeps, d)
+	ch := make(chan time.Time, 1)
+	ch <- time.Now()
+	return ch
+}
+
+func TestPublishRetryDeadLetter(t *testing.T) {
+	assert := assert.New(t)
+	dir, err := os.MkdirTemp("", "dead")
+	assert.NoError(err)
+	defer os.RemoveAll(dir)
+	clock := &sleepClock{}
+	dl := NewDeadLetter(filepath.Join(dir, "dead.jsonl"))
+	info := PubInfo{Name: "pub", Retry: RetryPolicy{MaxAttempts: 3, Backoff: time.Minute}, DeadLetter: dl, Clock: clock}
+	calls := map[string]int{}
+	ch := make(chan *Item, 3)
+	ch <- testItem("flaky")
+	ch <- testItem("down")
+	ch <- testItem("bad")
+	close(ch)
+	info.PublishByOne(ch, 0, func(it *Item) error {
+		calls[it.Title]++
+		switch {
+		case it.Title == "flaky" && calls[it.Title] < 3, it.Title == "down":
+			return &HTTPError{Code: 503, Status: "503 Service Unavailable"}
+		case it.Title == "bad":
+			return &HTTPError{Code: 400, Status: "400 Bad Request"}
+		}
+		return nil
+	})
+	assert.Equal(map[string]int{"flaky": 3, "down": 3, "bad": 1}, calls)
+	assert.Equal([]time.Duration{time.Minute, 2 * time.Minute, time.Minute, 2 * time.Minute}, clock.sleeps)
+
+	items, err := dl.List()
+	assert.NoError(err)
+	if assert.Len(items, 2) {
+		assert.Equal("down", items[0].Title)
+		assert.Equal("pub", items[0].Pub)
+		assert.Contains(items[0].Error, "503")
+		assert.Equal("http://x/bad", items[1].Link)
+	}
+}
+
+func TestDeadLetterTake(t *testing.T) {
+	assert := assert.New(t)
+	dir, err := os.MkdirTemp("", "dead")
+	assert.NoError(err)
+	defer os.RemoveAll(dir)
+	dl := NewDeadLetter(filepath.Join(dir, "dead.jsonl"))
+
+	items, err := dl.Take()
+	assert.NoError(err)
+	assert.Empty(items)
+
+	digest := newDigestItem("2 news", []*Item{testItem("a"), testItem("b")})
+	assert.NoError(dl.Add("a", testItem("one"), errors.New("e1")))
+	assert.NoError(dl.Add("b", digest, errors.New("e2")))
+	items, err = dl.Take()
+	assert.NoError(err)
+	if assert.Len(items, 2) {
+		assert.Equal("one", items[0].Title)
+		assert.Equal("src", items[0].Src.Name)
+		assert.Equal("2 news", items[1].Text)
+		assert.Equal("e2", items[1].Error)
+		assert.False(items[1].Failed.IsZero())
+	}
+	left, err := dl.List()
+	assert.NoError(err)
+	assert.Empty(left)
+
+	// crash before Done: the same items are taken again
+	items, err = dl.Take()
+	assert.NoError(err)
+	assert.Len(items, 2)
+	// the item written to the store that was opened before Take (by other process) isn't lost
+	f, err := os.OpenFile(dl.takenPath(), os.O_WRONLY|os.O_APPEND, 0600)
+	assert.NoError(err)
+	fmt.Fprintln(f, `{"title": "late", "pub": "a", "error": "e3"}`)
+	f.Close()
+	assert.NoError(dl.Done())
+	left, err = dl.List()
+	assert.NoError(err)
+	if assert.Len(left, 1) {
+		assert.Equal("late", left[0].Title)
+	}
+	left, err = dl.Take()
+	assert.NoError(err)
+	assert.Len(left, 1)
+	assert.NoError(dl.Done())
+
+	// put back as is
+	assert.NoError(dl.Put(items[0]))
+	left, err = dl.List()
+	assert.NoError(err)
+	if assert.Len(left, 1) {
+		assert.Equal(items[0].Failed.Unix(), left[0].Failed.Unix())
+	}
+}
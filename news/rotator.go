@@ -2,29 +2,83 @@ package news
 
 import (
 	"fmt"
-	"math/rand"
+	"sort"
+	"sync"
 	"time"
-
-	"github.com/willf/bitset"
 )
 
 // rotTickDefault - default tick duration
 var rotTickDefault = 60 * time.Second
 
-// DayInterval is a (bit) set 0..23 of day hours.
-type DayInterval struct {
-	set *bitset.BitSet
+// rotConcurrencyDefault - default limit of simultaneous polls in RotateAll mode
+var rotConcurrencyDefault = 4
+
+// RotationMode - how rotator picks the sources to poll on each tick
+type RotationMode string
+
+const (
+	// RotateRandom - one ready source per tick (default): priority source or weighted random one
+	RotateRandom RotationMode = "random"
+	// RotateAll - every ready source per tick, limited by Concurrency
+	RotateAll RotationMode = "all"
+)
+
+// RotationParams - rotator (source scheduler) params
+type RotationParams struct {
+	Tick time.Duration
+	Mode RotationMode
+	// Concurrency - RotateAll mode: max number of sources polled simultaneously, 4 by default
+	Concurrency int
+	// Jitter - RotateAll mode: each poll is delayed randomly up to Jitter, to spread requests within the tick
+	Jitter time.Duration
+	// Fair - RotateAll mode: least recently polled sources go first, when there are more ready
+	// sources than free slots (otherwise the order is random)
+	Fair bool
+}
+
+// Check - validates params, sets defaults
+func (p *RotationParams) Check() error {
+	switch p.Mode {
+	case "":
+		p.Mode = RotateRandom
+	case RotateRandom, RotateAll:
+	default:
+		return fmt.Errorf("rotation: unknown mode %q", p.Mode)
+	}
+	if p.Tick < 0 || p.Jitter < 0 || p.Concurrency < 0 {
+		return fmt.Errorf("rotation: negative param")
+	}
+	if p.Tick == 0 {
+		p.Tick = rotTickDefault
+	}
+	if p.Concurrency == 0 {
+		p.Concurrency = rotConcurrencyDefault
+	}
+	return nil
 }
 
 type rotator struct {
-	Tick  time.Duration
+	RotationParams
 	Elems []rotatorElem
 	guard Guard
+	slots chan struct{} // RotateAll mode semaphore
+	quit  <-chan struct{}
+	mu    sync.Mutex // guards elements last poll time (see touch)
+	clock Clock      // SystemClock if not set
+	rand  Rand       // SystemRand if not set
 }
 
 type rotatorElem struct {
 	Cooldown time.Duration
-	// Fn should not panic.
+	// CooldownFn - optional, overrides Cooldown (adaptive mode), must be safe for concurrent use.
+	CooldownFn func() time.Duration
+	// Ready - optional, overrides cooldown check (cron schedule)
+	Ready func(last, now time.Time) bool
+	// Weight - random mode pick chance, 1 if not set
+	Weight int
+	// Priority - ready priority elements are picked first (the least recently polled one)
+	Priority bool
+	// Fn is called in its own goroutine. Fn should not panic.
 	Fn   func(time.Time)
 	last time.Time
 }
@@ -34,95 +88,154 @@ func (rot *rotator) run(quit <-chan struct{}) {
 		panic("rotator: already started")
 	}
 	defer rot.guard.Unlock()
-	if rot.Tick == 0 {
-		rot.Tick = rotTickDefault
+	if err := rot.Check(); err != nil {
+		panic(err)
 	}
-	slog.Infow("Rotator started", "elemCount", len(rot.Elems), "tick", rot.Tick)
+	slog.Infow("Rotator started", "elemCount", len(rot.Elems), "tick", rot.Tick, "mode", rot.Mode)
 	defer slog.Infow("Rotator finished", "elemCount", len(rot.Elems))
 	if len(rot.Elems) == 0 {
 		panic("rotator: no elements")
 	}
+	rot.init(quit)
 
 	ready := make([]*rotatorElem, 0, len(rot.Elems))
 	for {
 		select {
-		case <-time.After(rot.Tick):
-			rot.onTick(ready, time.Now())
+		case <-rot.clock.After(rot.Tick):
+			rot.onTick(ready, rot.clock.Now())
 		case <-quit:
 			return
 		}
 	}
 }
 
+func (rot *rotator) init(quit <-chan struct{}) {
+	if rot.clock == nil {
+		rot.clock = SystemClock
+	}
+	if rot.rand == nil {
+		rot.rand = SystemRand
+	}
+	rot.quit = quit
+	rot.slots = make(chan struct{}, rot.Concurrency)
+}
+
 func (rot *rotator) onTick(ready []*rotatorElem, now time.Time) {
+	rot.mu.Lock()
+	defer rot.mu.Unlock()
 	ready = ready[:0]
 	for i := range rot.Elems {
 		e := &rot.Elems[i]
-		if now.Sub(e.last) >= e.Cooldown {
+		if e.ready(now) {
 			ready = append(ready, e)
 		}
 	}
-	var elem *rotatorElem
-	switch len(ready) {
-	case 0:
+	if len(ready) == 0 {
 		return
-	case 1:
-		elem = ready[0]
-	default:
-		elem = ready[rand.Intn(len(ready))]
+	}
+	if rot.Mode == RotateAll {
+		rot.launchAll(ready, now)
+		return
+	}
+	elem := pickPriority(ready)
+	if elem == nil {
+		elem = pickWeighted(ready, rot.rand)
 	}
 	elem.last = now
-	elem.Fn(now)
+	go elem.Fn(now)
 }
 
-func checkHour(h int) {
-	if !(0 <= h && h <= 23) {
-		panic("hour must be in range: 0..23")
+// pickPriority returns the least recently polled priority element, nil if there is none
+func pickPriority(ready []*rotatorElem) *rotatorElem {
+	var res *rotatorElem
+	for _, e := range ready {
+		if e.Priority && (res == nil || e.last.Before(res.last)) {
+			res = e
+		}
 	}
+	return res
 }
 
-// DayHoursFromTo creates DayInterval from begin(incl.) to end (incl.) hour
-// begin, end are day hours 0..23
-// end may be less that begin, since day hours are are cyclic :-)
-func DayHoursFromTo(begin, end int) DayInterval {
-	checkHour(begin)
-	checkHour(end)
-	set := bitset.New(24)
-	b, e := uint(begin), uint(end)
-	h := b
-	for {
-		set.Set(h)
-		h++
-		if h == 24 {
-			h = 0
+func pickWeighted(ready []*rotatorElem, rnd Rand) *rotatorElem {
+	if len(ready) == 1 {
+		return ready[0]
+	}
+	total := 0
+	for _, e := range ready {
+		total += e.weight()
+	}
+	n := rnd.Intn(total)
+	for _, e := range ready {
+		if n -= e.weight(); n < 0 {
+			return e
 		}
-		if h == b || h == e {
-			break
+	}
+	return ready[len(ready)-1]
+}
+
+func (e *rotatorElem) weight() int {
+	if e.Weight <= 0 {
+		return 1
+	}
+	return e.Weight
+}
+
+// launchAll polls ready elements while there are free slots (priority ones first), the rest wait for the next tick
+func (rot *rotator) launchAll(ready []*rotatorElem, now time.Time) {
+	if !rot.Fair {
+		rot.rand.Shuffle(len(ready), func(i, j int) {
+			ready[i], ready[j] = ready[j], ready[i]
+		})
+	}
+	sort.SliceStable(ready, func(i, j int) bool {
+		a, b := ready[i], ready[j]
+		if a.Priority != b.Priority {
+			return a.Priority
+		}
+		return rot.Fair && a.last.Before(b.last)
+	})
+	for i, e := range ready {
+		select {
+		case rot.slots <- struct{}{}:
+		default:
+			slog.Debugw("rotator_busy", "postponed", len(ready)-i)
+			return
+		}
+		e.last = now
+		var delay time.Duration
+		if rot.Jitter > 0 {
+			delay = time.Duration(rot.rand.Int63n(int64(rot.Jitter)))
 		}
+		go rot.launch(e.Fn, delay, now)
 	}
-	return DayInterval{set}
 }
 
-func (di DayInterval) ContainsHour(h int) bool { //nolint
-	if di.set == nil {
-		return false
+func (rot *rotator) launch(fn func(time.Time), delay time.Duration, now time.Time) {
+	defer func() { <-rot.slots }()
+	if delay > 0 {
+		select {
+		case <-rot.clock.After(delay):
+		case <-rot.quit:
+			return
+		}
 	}
-	return di.set.Test(uint(h))
+	fn(now)
 }
 
-func (di DayInterval) ContainsTime(t time.Time) bool { //nolint
-	return di.ContainsHour(t.Hour())
+// touch - marks i-th element as polled at the given time (manual fetch), i.e. resets its cooldown
+func (rot *rotator) touch(i int, now time.Time) {
+	rot.mu.Lock()
+	defer rot.mu.Unlock()
+	rot.Elems[i].last = now
 }
 
-func (di DayInterval) String() string {
-	if di.set == nil {
-		return "{}"
+func (e *rotatorElem) ready(now time.Time) bool {
+	if e.Ready != nil {
+		return e.Ready(e.last, now)
 	}
-	a := make([]int8, 0, di.set.Count())
-	for i := 0; i < 24; i++ {
-		if di.set.Test(uint(i)) {
-			a = append(a, int8(i))
-		}
+	cd := e.Cooldown
+	if e.CooldownFn != nil {
+		cd = e.CooldownFn()
 	}
-	return fmt.Sprintf("%v", a)
+	return now.Sub(e.last) >= cd
 }
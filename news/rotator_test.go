@@ -0,0 +1,142 @@
+package news
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testRotator(p RotationParams, n int, calls chan<- int, release <-chan struct{}) *rotator {
+	rot := &rotator{RotationParams: p}
+	if err := rot.Check(); err != nil {
+		panic(err)
+	}
+	rot.init(make(chan struct{}))
+	for i := 0; i < n; i++ {
+		i := i
+		rot.Elems = append(rot.Elems, rotatorElem{
+			Cooldown: time.Minute,
+			Fn: func(time.Time) {
+				calls <- i
+				if release != nil {
+					<-release
+				}
+			},
+		})
+	}
+	return rot
+}
+
+func recvCalls(ch <-chan int, n int) []int {
+	var res []int
+	for i := 0; i < n; i++ {
+		select {
+		case v := <-ch:
+			res = append(res, v)
+		case <-time.After(200 * time.Millisecond):
+			return res
+		}
+	}
+	return res
+}
+
+func TestRotatorRandomMode(t *testing.T) {
+	assert := assert.New(t)
+	calls := make(chan int, 8)
+	rot := testRotator(RotationParams{}, 3, calls, nil)
+	assert.Equal(RotateRandom, rot.Mode)
+	now := time.Now()
+	rot.onTick(nil, now)
+	assert.Len(recvCalls(calls, 1), 1)
+	rot.onTick(nil, now.Add(time.Second))
+	rot.onTick(nil, now.Add(2*time.Second))
+	assert.Len(recvCalls(calls, 2), 2)
+	// all are cooling down
+	rot.onTick(nil, now.Add(3*time.Second))
+	assert.Len(recvCalls(calls, 1), 0)
+}
+
+func TestRotatorAllMode(t *testing.T) {
+	assert := assert.New(t)
+	calls := make(chan int, 8)
+	release := make(chan struct{})
+	rot := testRotator(RotationParams{Mode: RotateAll, Concurrency: 2}, 3, calls, release)
+	now := time.Now()
+	rot.onTick(nil, now)
+	first := recvCalls(calls, 3)
+	assert.Len(first, 2, "limited by concurrency")
+
+	// no free slots: nothing is launched, postponed element stays ready
+	rot.onTick(nil, now.Add(time.Second))
+	assert.Len(recvCalls(calls, 1), 0)
+
+	release <- struct{}{}
+	release <- struct{}{}
+	time.Sleep(50 * time.Millisecond)
+	rot.onTick(nil, now.Add(2*time.Second))
+	rest := recvCalls(calls, 3)
+	assert.Len(rest, 1)
+	assert.NotContains(first, rest[0])
+	release <- struct{}{}
+}
+
+func TestRotatorFair(t *testing.T) {
+	assert := assert.New(t)
+	calls := make(chan int, 8)
+	release := make(chan struct{})
+	rot := testRotator(RotationParams{Mode: RotateAll, Concurrency: 1, Fair: true}, 3, calls, release)
+	now := time.Now()
+	rot.Elems[0].last = now.Add(-2 * time.Minute)
+	rot.Elems[1].last = now.Add(-4 * time.Minute)
+	rot.Elems[2].last = now.Add(-3 * time.Minute)
+	for i, want := range []int{1, 2, 0} {
+		rot.onTick(nil, now.Add(time.Duration(i)*time.Second))
+		assert.Equal([]int{want}, recvCalls(calls, 1))
+		release <- struct{}{}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+func TestRotationParamsCheck(t *testing.T) {
+	assert := assert.New(t)
+	p := RotationParams{Mode: RotateAll}
+	assert.NoError(p.Check())
+	assert.Equal(rotTickDefault, p.Tick)
+	assert.Equal(rotConcurrencyDefault, p.Concurrency)
+	p = RotationParams{Mode: "sometimes"}
+	assert.Error(p.Check())
+	p = RotationParams{Jitter: -time.Second}
+	assert.Error(p.Check())
+}
+
+func TestRotatorPriority(t *testing.T) {
+	assert := assert.New(t)
+	calls := make(chan int, 8)
+	rot := testRotator(RotationParams{}, 4, calls, nil)
+	rot.Elems[2].Priority = true
+	rot.Elems[3].Priority = true
+	now := time.Now()
+	rot.Elems[3].last = now.Add(-2 * time.Hour)
+	rot.Elems[2].last = now.Add(-time.Hour)
+	rot.onTick(nil, now)
+	rot.onTick(nil, now.Add(time.Second))
+	assert.Equal([]int{3, 2}, recvCalls(calls, 2))
+	// priority element becomes ready again (once per its cooldown) and is picked first
+	rot.onTick(nil, now.Add(time.Minute))
+	assert.Equal([]int{3}, recvCalls(calls, 1))
+}
+
+func TestRotatorWeighted(t *testing.T) {
+	assert := assert.New(t)
+	rnd := rand.New(rand.NewSource(1))
+	ready := []*rotatorElem{{Weight: 1}, {Weight: 9}, {}}
+	counts := map[*rotatorElem]int{}
+	for i := 0; i < 11000; i++ {
+		counts[pickWeighted(ready, rnd)]++
+	}
+	assert.InDelta(1000, counts[ready[0]], 300)
+	assert.InDelta(9000, counts[ready[1]], 300)
+	assert.InDelta(1000, counts[ready[2]], 300)
+}
@@ -0,0 +1,211 @@
+package news
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule is a set of weekly time windows (minute granularity), e.g. mute intervals.
+// Zero value is empty schedule, it contains no time.
+type Schedule struct {
+	windows []schedWindow
+	loc     *time.Location // time zone of the windows, nil means the location of the checked time
+}
+
+// schedWindow - [from, to) minutes of the day on the given week days.
+// If to <= from, the window lasts over midnight, days refer to the start day.
+type schedWindow struct {
+	days     uint8 // bit i is set for time.Weekday(i)
+	from, to int
+}
+
+const (
+	allWeekDays = 1<<7 - 1
+	dayMinutes  = 24 * 60
+)
+
+var weekDayNames = []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}
+
+// ParseSchedule parses window specs: "[days] [HH:MM-HH:MM]", days is comma separated list of week days or
+// day ranges: "Mon-Fri 23:00-07:00", "Sat,Sun", "12:30-14:00". Omitted days means every day,
+// omitted time range means the whole day.
+func ParseSchedule(specs ...string) (Schedule, error) {
+	var s Schedule
+	for _, spec := range specs {
+		w, err := parseSchedWindow(spec)
+		if err != nil {
+			return Schedule{}, fmt.Errorf("schedule %q: %s", spec, err)
+		}
+		s.windows = append(s.windows, w)
+	}
+	return s, nil
+}
+
+func parseSchedWindow(spec string) (schedWindow, error) {
+	w := schedWindow{days: allWeekDays, from: 0, to: dayMinutes}
+	fields := strings.Fields(spec)
+	if len(fields) == 0 || len(fields) > 2 {
+		return w, fmt.Errorf("bad format")
+	}
+	if !strings.Contains(fields[0], ":") {
+		days, err := parseWeekDays(fields[0])
+		if err != nil {
+			return w, err
+		}
+		w.days = days
+		fields = fields[1:]
+	}
+	if len(fields) == 0 {
+		return w, nil
+	}
+	rng := strings.Split(fields[0], "-")
+	if len(rng) != 2 {
+		return w, fmt.Errorf("bad time range: %s", fields[0])
+	}
+	var err error
+	if w.from, err = parseDayMinute(rng[0]); err != nil {
+		return w, err
+	}
+	if w.to, err = parseDayMinute(rng[1]); err != nil {
+		return w, err
+	}
+	if w.from == dayMinutes {
+		return w, fmt.Errorf("bad time range: %s", fields[0])
+	}
+	return w, nil
+}
+
+func parseWeekDays(s string) (uint8, error) {
+	var days uint8
+	for _, part := range strings.Split(s, ",") {
+		rng := strings.Split(part, "-")
+		if len(rng) > 2 {
+			return 0, fmt.Errorf("bad days: %s", part)
+		}
+		from, err := parseWeekDay(rng[0])
+		if err != nil {
+			return 0, err
+		}
+		to := from
+		if len(rng) == 2 {
+			if to, err = parseWeekDay(rng[1]); err != nil {
+				return 0, err
+			}
+		}
+		for d := from; ; d = (d + 1) % 7 {
+			days |= 1 << uint(d)
+			if d == to {
+				break
+			}
+		}
+	}
+	return days, nil
+}
+
+func parseWeekDay(s string) (int, error) {
+	s = strings.ToLower(s)
+	for i, n := range weekDayNames {
+		if strings.HasPrefix(s, n) {
+			return i, nil
+		}
+	}
+	return 0, fmt.Errorf("bad week day: %s", s)
+}
+
+// parseDayMinute parses "HH:MM" (or "HH"), "24:00" is the end of the day
+func parseDayMinute(s string) (int, error) {
+	hm := strings.SplitN(s, ":", 2)
+	h, err := strconv.Atoi(hm[0])
+	if err != nil {
+		return 0, fmt.Errorf("bad time: %s", s)
+	}
+	m := 0
+	if len(hm) == 2 {
+		if m, err = strconv.Atoi(hm[1]); err != nil {
+			return 0, fmt.Errorf("bad time: %s", s)
+		}
+	}
+	v := h*60 + m
+	if h < 0 || m < 0 || m > 59 || v > dayMinutes {
+		return 0, fmt.Errorf("bad time: %s", s)
+	}
+	return v, nil
+}
+
+// DayHoursFromTo creates daily window from begin hour (incl.) till end hour (excl.)
+// begin, end are day hours 0..23
+// end may be less that begin, since day hours are are cyclic :-)
+func DayHoursFromTo(begin, end int) Schedule {
+	checkHour(begin)
+	checkHour(end)
+	to := end * 60
+	if begin == end {
+		to = begin*60 + dayMinutes
+	}
+	return Schedule{windows: []schedWindow{{days: allWeekDays, from: begin * 60, to: to % dayMinutes}}}
+}
+
+func checkHour(h int) {
+	if !(0 <= h && h <= 23) {
+		panic("hour must be in range: 0..23")
+	}
+}
+
+// IsEmpty - true if schedule has no windows
+func (s Schedule) IsEmpty() bool {
+	return len(s.windows) == 0
+}
+
+// In returns the schedule whose windows are in the given time zone
+func (s Schedule) In(loc *time.Location) Schedule {
+	s.loc = loc
+	return s
+}
+
+// ContainsTime - true if t is within any window.
+// Schedule location is used, if it is set, otherwise t's location
+func (s Schedule) ContainsTime(t time.Time) bool {
+	if s.loc != nil {
+		t = t.In(s.loc)
+	}
+	m := t.Hour()*60 + t.Minute()
+	wd := uint(t.Weekday())
+	yd := (wd + 6) % 7 // yesterday
+	for _, w := range s.windows {
+		if w.from < w.to {
+			if w.days&(1<<wd) != 0 && w.from <= m && m < w.to {
+				return true
+			}
+			continue
+		}
+		if (w.days&(1<<wd) != 0 && m >= w.from) || (w.days&(1<<yd) != 0 && m < w.to) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s Schedule) String() string {
+	if len(s.windows) == 0 {
+		return "{}"
+	}
+	parts := make([]string, 0, len(s.windows))
+	for _, w := range s.windows {
+		var days []string
+		if w.days != allWeekDays {
+			for i, n := range weekDayNames {
+				if w.days&(1<<uint(i)) != 0 {
+					days = append(days, n)
+				}
+			}
+		}
+		p := fmt.Sprintf("%02d:%02d-%02d:%02d", w.from/60, w.from%60, w.to/60, w.to%60)
+		if len(days) != 0 {
+			p = strings.Join(days, ",") + " " + p
+		}
+		parts = append(parts, p)
+	}
+	return "{" + strings.Join(parts, "; ") + "}"
+}
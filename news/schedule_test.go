@@ -0,0 +1,106 @@
+package news
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// 2018-03-05 is Monday
+func at(day, h, m int) time.Time {
+	return time.Date(2018, 3, day, h, m, 0, 0, time.UTC)
+}
+
+func TestSchedule(t *testing.T) {
+	s, err := ParseSchedule("Sat,Sun", "Mon-Fri 23:30-07:00", "12:00-12:45")
+	assert.NoError(t, err)
+	assert.True(t, s.ContainsTime(at(10, 15, 0)), "saturday")
+	assert.True(t, s.ContainsTime(at(11, 23, 59)), "sunday")
+	assert.False(t, s.ContainsTime(at(5, 6, 0)), "monday morning: window started on sunday")
+	assert.True(t, s.ContainsTime(at(5, 23, 30)))
+	assert.True(t, s.ContainsTime(at(6, 6, 59)), "tuesday morning")
+	assert.False(t, s.ContainsTime(at(6, 7, 0)))
+	assert.True(t, s.ContainsTime(at(10, 6, 0)), "saturday morning: friday window")
+	assert.True(t, s.ContainsTime(at(7, 12, 44)))
+	assert.False(t, s.ContainsTime(at(7, 12, 45)))
+	assert.Equal(t, "{sun,sat 00:00-24:00; mon,tue,wed,thu,fri 23:30-07:00; 12:00-12:45}", s.String())
+
+	for _, bad := range []string{"", "Xyz", "Mon 25:00-26:00", "10:00", "Mon 10:61-11:00", "a b c"} {
+		_, err := ParseSchedule(bad)
+		assert.Error(t, err, bad)
+	}
+	assert.False(t, Schedule{}.ContainsTime(at(5, 0, 0)))
+}
+
+func TestDayHoursFromTo(t *testing.T) {
+	di := DayHoursFromTo(20, 5)
+	assert.True(t, di.ContainsTime(at(5, 20, 0)))
+	assert.True(t, di.ContainsTime(at(5, 4, 59)))
+	assert.False(t, di.ContainsTime(at(5, 5, 0)))
+	assert.False(t, di.ContainsTime(at(5, 19, 59)))
+	all := DayHoursFromTo(3, 3)
+	assert.True(t, all.ContainsTime(at(5, 2, 0)))
+	assert.True(t, all.ContainsTime(at(5, 3, 0)))
+}
+
+func TestCron(t *testing.T) {
+	c, err := ParseCron("*/15 8-20 * * Mon-Fri")
+	assert.NoError(t, err)
+	assert.Equal(t, at(5, 8, 15), c.Next(at(5, 8, 0)))
+	assert.Equal(t, at(5, 9, 0), c.Next(at(5, 8, 50)))
+	assert.Equal(t, at(12, 8, 0), c.Next(at(9, 20, 45)), "friday evening -> monday")
+
+	c, err = ParseCron("30 6 1,15 * sun")
+	assert.NoError(t, err)
+	assert.Equal(t, at(11, 6, 30), c.Next(at(5, 0, 0)), "dom or dow")
+	assert.Equal(t, at(15, 6, 30), c.Next(at(11, 6, 30)))
+
+	c, _ = ParseCron("0 0 1 jan *")
+	assert.Equal(t, time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC), c.Next(at(5, 0, 0)))
+	c, _ = ParseCron("0 0 31 2 *")
+	assert.True(t, c.Next(at(5, 0, 0)).IsZero())
+
+	c, _ = ParseCron("0 * * * *")
+	assert.True(t, c.Due(time.Time{}, at(5, 0, 10)))
+	assert.False(t, c.Due(at(5, 10, 0), at(5, 10, 59)))
+	assert.True(t, c.Due(at(5, 10, 0), at(5, 11, 0)))
+
+	for _, bad := range []string{"* * * *", "60 * * * *", "* * 0 * *", "*/0 * * * *", "5-1 * * * *", "* * * foo *"} {
+		_, err := ParseCron(bad)
+		assert.Error(t, err, bad)
+	}
+}
+
+func TestScheduleTimezone(t *testing.T) {
+	msk, err := time.LoadLocation("Europe/Moscow")
+	assert.NoError(t, err)
+	s, _ := ParseSchedule("20:00-05:00")
+	s = s.In(msk)
+	// clock of the host in UTC: 17:00 UTC is 20:00 MSK
+	assert.True(t, s.ContainsTime(time.Date(2018, 3, 5, 17, 0, 0, 0, time.UTC)))
+	assert.False(t, s.ContainsTime(time.Date(2018, 3, 5, 2, 0, 0, 0, time.UTC)))
+
+	// DST: Berlin switches to summer time on 2018-03-25 at 02:00 -> 03:00
+	ber, err := time.LoadLocation("Europe/Berlin")
+	assert.NoError(t, err)
+	s, _ = ParseSchedule("Sun 03:00-04:00")
+	s = s.In(ber)
+	assert.False(t, s.ContainsTime(time.Date(2018, 3, 25, 0, 59, 0, 0, time.UTC)), "01:59 CET")
+	assert.True(t, s.ContainsTime(time.Date(2018, 3, 25, 1, 0, 0, 0, time.UTC)), "03:00 CEST")
+	assert.False(t, s.ContainsTime(time.Date(2018, 3, 25, 2, 0, 0, 0, time.UTC)), "04:00 CEST")
+
+	c, _ := ParseCron("0 9 * * *")
+	c = c.In(ber)
+	assert.Equal(t, time.Date(2018, 3, 24, 8, 0, 0, 0, time.UTC), c.Next(time.Date(2018, 3, 24, 0, 0, 0, 0, time.UTC)).UTC(), "CET")
+	assert.Equal(t, time.Date(2018, 3, 25, 7, 0, 0, 0, time.UTC), c.Next(time.Date(2018, 3, 24, 9, 0, 0, 0, time.UTC)).UTC(), "CEST")
+}
+
+func TestPubLocalize(t *testing.T) {
+	msk, _ := time.LoadLocation("Europe/Moscow")
+	published := time.Date(2018, 3, 5, 17, 0, 0, 0, time.UTC)
+	it := &Item{ItemParams: ItemParams{Title: "t", Published: &published}}
+	assert.Equal(t, "05.03 20:00", (&PubInfo{Location: msk}).localize(it).DateFmt)
+	assert.Equal(t, "05.03 17:00", (&PubInfo{Location: time.UTC}).localize(it).DateFmt)
+	assert.Empty(t, it.DateFmt, "shared item isn't modified")
+}
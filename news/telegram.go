@@ -0,0 +1,238 @@
+package news
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	neturl "net/url"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/dlepex/newsmaker/strext"
+)
+
+// Telegram parse modes
+const (
+	TgPlain      = ""
+	TgMarkdown   = "Markdown" // legacy markdown
+	TgMarkdownV2 = "MarkdownV2"
+	TgHTML       = "HTML"
+)
+
+// TelegramAPI - default Bot API url
+var TelegramAPI = "https://api.telegram.org"
+
+// TelegramMaxRetries - default number of retries of the request that was rate limited (429)
+var TelegramMaxRetries = 3
+
+// tgCaptionMax - max photo caption length, longer messages are sent as text
+const tgCaptionMax = 1024
+
+// TelegramMaxLength - max message length, default PubInfo.MaxLength of telegram pub
+const TelegramMaxLength = 4096
+
+// TelegramPubParams - Telegram Bot API publisher params
+type TelegramPubParams struct {
+	PubInfo
+	Token     string
+	ChatID    string // numeric chat id or @channelusername
+	ParseMode string // "MarkdownV2", "HTML", "Markdown" or "" (plain text)
+	ItemStringer
+	ThreadID       int64 // optional, forum topic (message_thread_id)
+	DisablePreview bool  // disable_web_page_preview
+	Silent         bool  // disable_notification
+	// Photo - items with image are sent by sendPhoto, the message is the caption
+	Photo bool
+	Pause time.Duration // pause between messages
+	// MaxRetries - number of retries after 429 (retry_after is honoured), TelegramMaxRetries by default
+	MaxRetries int
+	API        string // Bot API url, TelegramAPI by default
+	Client     *http.Client
+}
+
+type telegramPub struct {
+	TelegramPubParams
+	sleep func(time.Duration)
+}
+
+// TelegramError - Bot API error response
+type TelegramError struct {
+	Code        int
+	Description string
+	RetryAfter  time.Duration
+}
+
+func (e *TelegramError) Error() string {
+	return fmt.Sprintf("telegram: %d %s", e.Code, e.Description)
+}
+
+// NewTelegramPub creates publisher that sends items via Telegram Bot API
+func NewTelegramPub(p TelegramPubParams) (Pub, error) {
+	if strext.IsBlank(p.Token) || strext.IsBlank(p.ChatID) {
+		return nil, errors.New("telegram pub: token and chat_id required")
+	}
+	switch p.ParseMode {
+	case TgPlain, TgMarkdown, TgMarkdownV2, TgHTML:
+	default:
+		return nil, fmt.Errorf("telegram pub: unknown parse mode: %s", p.ParseMode)
+	}
+	if p.ItemStringer == nil {
+		p.ItemStringer = NewItemTemplateStringer(TelegramTemplate(p.ParseMode))
+	}
+	if p.MaxRetries == 0 {
+		p.MaxRetries = TelegramMaxRetries
+	}
+	if p.MaxLength == 0 || p.MaxLength > TelegramMaxLength {
+		p.MaxLength = TelegramMaxLength
+	}
+	p.Markup = p.ParseMode
+	if p.API == "" {
+		p.API = TelegramAPI
+	}
+	if p.Client == nil {
+		p.Client = &http.Client{Timeout: time.Minute}
+	}
+	return &telegramPub{TelegramPubParams: p, sleep: time.Sleep}, nil
+}
+
+// TelegramTemplate - default item template for the parse mode
+func TelegramTemplate(parseMode string) string {
+	switch parseMode {
+	case TgMarkdownV2:
+		return "*{{md .Title}}* {{md .DateFmt}}\n{{md .Src.Name}} {{md .Link}}"
+	case TgHTML:
+		return "<b>{{html .Title}}</b> {{html .DateFmt}}\n{{html .Src.Name}} {{html .Link}}"
+	case TgMarkdown:
+		return "*{{.Title}}* {{.DateFmt}} \n{{.Src.Name}} {{.Link}}"
+	}
+	return "{{.Title}} {{.DateFmt}}\n{{.Src.Name}} {{.Link}}"
+}
+
+func (pub *telegramPub) Info() *PubInfo {
+	return &pub.PubInfo
+}
+
+func (pub *telegramPub) Publish(ch <-chan *Item) {
+	pub.publishParts(ch, pub.Pause, pub.send)
+}
+
+func (pub *telegramPub) send(it *Item, ps *partSender) error {
+	parts := pub.messages(it, pub.ItemStringer)
+	if text := parts[0]; pub.Photo && it.Image != "" && it.Text == "" && len(parts) == 1 && utf8.RuneCountInString(text) <= tgCaptionMax {
+		ps.waitOnce() // text fallback doesn't wait again
+		err := pub.call("sendPhoto", pub.message(map[string]interface{}{"photo": it.Image, "caption": text}))
+		if err == nil {
+			return nil
+		}
+		slog.Infow("tg_photo_error", "pub", pub.Name, "err", err, "image", it.Image)
+	}
+	return ps.send(it, parts, func(text string) error {
+		return pub.call("sendMessage", pub.message(map[string]interface{}{
+			"text":                     text,
+			"disable_web_page_preview": pub.DisablePreview,
+		}))
+	})
+}
+
+func (pub *telegramPub) message(m map[string]interface{}) map[string]interface{} {
+	m["chat_id"] = pub.ChatID
+	if pub.ParseMode != TgPlain {
+		m["parse_mode"] = pub.ParseMode
+	}
+	if pub.Silent {
+		m["disable_notification"] = true
+	}
+	if pub.ThreadID != 0 {
+		m["message_thread_id"] = pub.ThreadID
+	}
+	return m
+}
+
+// call - Bot API request, it's retried after 429. Message that can't be parsed is resent as plain text.
+func (pub *telegramPub) call(method string, m map[string]interface{}) error {
+	for attempt := 0; ; attempt++ {
+		err := pub.post(method, m)
+		var tgErr *TelegramError
+		if !errors.As(err, &tgErr) {
+			return err
+		}
+		switch {
+		case tgErr.Code == http.StatusTooManyRequests && attempt < pub.MaxRetries:
+			slog.Infow("tg_retry_after", "pub", pub.Name, "retry_after", tgErr.RetryAfter)
+			pub.sleep(tgErr.RetryAfter)
+		case tgErr.Code == http.StatusBadRequest && m["parse_mode"] != nil &&
+			strings.Contains(tgErr.Description, "can't parse entities"):
+			slog.Infow("tg_parse_error", "pub", pub.Name, "err", tgErr.Description)
+			delete(m, "parse_mode")
+		default:
+			return err
+		}
+	}
+}
+
+func (pub *telegramPub) post(method string, m map[string]interface{}) error {
+	body, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	url := fmt.Sprintf("%s/bot%s/%s", strings.TrimSuffix(pub.API, "/"), pub.Token, method)
+	r, err := pub.Client.Post(url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		var ue *neturl.Error
+		if errors.As(err, &ue) {
+			ue.URL = strings.ReplaceAll(ue.URL, pub.Token, "<token>") // don't log the token
+		}
+		return fmt.Errorf("telegram %s: %w", method, err)
+	}
+	defer r.Body.Close() // nolint:errcheck
+	var resp struct {
+		OK          bool   `json:"ok"`
+		ErrorCode   int    `json:"error_code"`
+		Description string `json:"description"`
+		Parameters  struct {
+			RetryAfter int `json:"retry_after"`
+		} `json:"parameters"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&resp); err != nil {
+		return fmt.Errorf("telegram %s: bad response: %v (%s)", method, err, r.Status)
+	}
+	if !resp.OK {
+		if resp.ErrorCode == 0 {
+			resp.ErrorCode = r.StatusCode
+		}
+		return &TelegramError{
+			Code:        resp.ErrorCode,
+			Description: resp.Description,
+			RetryAfter:  time.Duration(resp.Parameters.RetryAfter) * time.Second,
+		}
+	}
+	return nil
+}
+
+// mdV2Special - characters that must be escaped in MarkdownV2 text
+const mdV2Special = "_*[]()~`>#+-=|{}.!\\"
+
+// EscapeMarkdownV2 escapes MarkdownV2 special characters
+func EscapeMarkdownV2(s string) string {
+	return escapeChars(s, mdV2Special)
+}
+
+// EscapeMarkdownV2URL escapes url within MarkdownV2 inline link: [text](url)
+func EscapeMarkdownV2URL(s string) string {
+	return escapeChars(s, ")\\")
+}
+
+func escapeChars(s, chars string) string {
+	var b strings.Builder
+	b.Grow(len(s) + 8)
+	for _, r := range s {
+		if r < utf8.RuneSelf && strings.ContainsRune(chars, r) {
+			b.WriteByte('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
@@ -0,0 +1,178 @@
+package news
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeBotAPI - local stand-in of Telegram Bot API, reply decides the response of each request
+type fakeBotAPI struct {
+	mu    sync.Mutex
+	calls []fakeBotCall
+	reply func(method string, msg map[string]interface{}) (int, string)
+}
+
+type fakeBotCall struct {
+	Method string
+	Msg    map[string]interface{}
+}
+
+func (f *fakeBotAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(r.URL.Path, "/")
+	if len(parts) != 3 || parts[1] != "botTOKEN" {
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprint(w, `{"ok":false,"error_code":404,"description":"Not Found"}`)
+		return
+	}
+	var msg map[string]interface{}
+	if err := json.NewDecoder(r.Body).Decode(&msg); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	f.mu.Lock()
+	f.calls = append(f.calls, fakeBotCall{parts[2], msg})
+	f.mu.Unlock()
+	st, body := http.StatusOK, `{"ok":true,"result":{}}`
+	if f.reply != nil {
+		st, body = f.reply(parts[2], msg)
+	}
+	w.WriteHeader(st)
+	fmt.Fprint(w, body)
+}
+
+func newTestTelegramPub(t *testing.T, api *fakeBotAPI, p TelegramPubParams) (*telegramPub, *[]time.Duration) {
+	srv := httptest.NewServer(api)
+	t.Cleanup(srv.Close)
+	p.PubInfo = PubInfo{Name: "tg", Location: time.UTC}
+	p.Token, p.ChatID, p.API = "TOKEN", "-100", srv.URL
+	pub, err := NewTelegramPub(p)
+	assert.NoError(t, err)
+	tg := pub.(*telegramPub)
+	var sleeps []time.Duration
+	tg.sleep = func(d time.Duration) { sleeps = append(sleeps, d) }
+	return tg, &sleeps
+}
+
+func TestTelegramPubSend(t *testing.T) {
+	assert := assert.New(t)
+	api := &fakeBotAPI{}
+	pub, _ := newTestTelegramPub(t, api, TelegramPubParams{
+		ParseMode: TgMarkdownV2, DisablePreview: true, Silent: true, ThreadID: 7,
+	})
+	it := testItem("Hello. World!")
+	published := time.Date(2018, 3, 5, 10, 30, 0, 0, time.UTC)
+	it.Published = &published
+	assert.NoError(pub.send(it, nil))
+	if assert.Len(api.calls, 1) {
+		c := api.calls[0]
+		assert.Equal("sendMessage", c.Method)
+		assert.Equal("*Hello\\. World\\!* 05\\.03 10:30\nsrc http://x/Hello\\. World\\!", c.Msg["text"])
+		assert.Equal("-100", c.Msg["chat_id"])
+		assert.Equal("MarkdownV2", c.Msg["parse_mode"])
+		assert.Equal(true, c.Msg["disable_web_page_preview"])
+		assert.Equal(true, c.Msg["disable_notification"])
+		assert.Equal(7.0, c.Msg["message_thread_id"])
+	}
+}
+
+func TestTelegramPubRetryAfter(t *testing.T) {
+	assert := assert.New(t)
+	n := 0
+	api := &fakeBotAPI{reply: func(string, map[string]interface{}) (int, string) {
+		if n++; n <= 2 {
+			return http.StatusTooManyRequests, `{"ok":false,"error_code":429,"description":"Too Many Requests: retry after 5","parameters":{"retry_after":5}}`
+		}
+		return http.StatusOK, `{"ok":true,"result":{}}`
+	}}
+	pub, sleeps := newTestTelegramPub(t, api, TelegramPubParams{})
+	assert.NoError(pub.send(testItem("news"), nil))
+	assert.Len(api.calls, 3)
+	assert.Equal([]time.Duration{5 * time.Second, 5 * time.Second}, *sleeps)
+
+	// retries are limited
+	n = -10
+	err := pub.send(testItem("news"), nil)
+	if assert.Error(err) {
+		assert.Equal(http.StatusTooManyRequests, err.(*TelegramError).Code)
+	}
+}
+
+func TestTelegramPubPhotoFallback(t *testing.T) {
+	assert := assert.New(t)
+	api := &fakeBotAPI{reply: func(method string, msg map[string]interface{}) (int, string) {
+		if method == "sendPhoto" && msg["photo"] == "http://x/bad.png" {
+			return http.StatusBadRequest, `{"ok":false,"error_code":400,"description":"Bad Request: wrong file identifier/HTTP URL specified"}`
+		}
+		if msg["parse_mode"] != nil && strings.Contains(fmt.Sprint(msg["text"]), "*") {
+			return http.StatusBadRequest, `{"ok":false,"error_code":400,"description":"Bad Request: can't parse entities: Can't find end of Bold entity at byte offset 0"}`
+		}
+		return http.StatusOK, `{"ok":true,"result":{}}`
+	}}
+	pub, _ := newTestTelegramPub(t, api, TelegramPubParams{
+		ParseMode:    TgHTML,
+		Photo:        true,
+		ItemStringer: NewItemTemplateStringer("<b>{{html .Title}}</b>"),
+	})
+	it := testItem("A & B")
+	it.Image = "http://x/a.png"
+	waits := 0
+	ps := &partSender{wait: func() { waits++ }}
+	assert.NoError(pub.send(it, ps))
+	assert.Equal(1, waits)
+	if assert.Len(api.calls, 1) {
+		assert.Equal("sendPhoto", api.calls[0].Method)
+		assert.Equal("<b>A &amp; B</b>", api.calls[0].Msg["caption"])
+		assert.Equal("http://x/a.png", api.calls[0].Msg["photo"])
+	}
+
+	// bad photo: sent as text
+	api.calls = nil
+	it.Image = "http://x/bad.png"
+	ps.waited = false // new attempt, see publishParts
+	assert.NoError(pub.send(it, ps))
+	assert.Equal(2, waits)
+	if assert.Len(api.calls, 2) {
+		assert.Equal("sendMessage", api.calls[1].Method)
+	}
+
+	// message that can't be parsed is resent as plain text
+	api.calls = nil
+	pub.ParseMode = TgMarkdown
+	pub.Photo = false
+	pub.ItemStringer = NewItemTemplateStringer("*{{.Title}}")
+	assert.NoError(pub.send(it, nil))
+	if assert.Len(api.calls, 2) {
+		assert.Equal("Markdown", api.calls[0].Msg["parse_mode"])
+		assert.Nil(api.calls[1].Msg["parse_mode"])
+	}
+}
+
+func TestTelegramPubErrors(t *testing.T) {
+	assert := assert.New(t)
+	_, err := NewTelegramPub(TelegramPubParams{ChatID: "1"})
+	assert.Error(err)
+	_, err = NewTelegramPub(TelegramPubParams{Token: "t", ChatID: "1", ParseMode: "markdown3"})
+	assert.Error(err)
+
+	api := &fakeBotAPI{}
+	pub, _ := newTestTelegramPub(t, api, TelegramPubParams{})
+	pub.Token = "WRONG"
+	err = pub.send(testItem("news"), nil)
+	if assert.Error(err) {
+		assert.Equal(http.StatusNotFound, err.(*TelegramError).Code)
+	}
+}
+
+func TestEscapeMarkdownV2(t *testing.T) {
+	assert := assert.New(t)
+	assert.Equal(`a\_b\*c\[d\]\(e\) \~\`+"`"+`\>\#\+\-\=\|\{\}\.\!\\ ё`, EscapeMarkdownV2("a_b*c[d](e) ~`>#+-=|{}.!\\ ё"))
+	assert.Equal(`http://x/a_(b\)\\`, EscapeMarkdownV2URL(`http://x/a_(b)\`))
+}
@@ -0,0 +1,156 @@
+package news
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/dlepex/newsmaker/strext"
+)
+
+// WebhookBodyDefault - default request body template (slack, mattermost and similar incoming webhooks)
+const WebhookBodyDefault = `{"text": {{json .Message}}}`
+
+// WebhookPubParams - webhook publisher params
+type WebhookPubParams struct {
+	PubInfo
+	Method  string // POST by default
+	URL     string
+	Headers map[string]string
+	// Body - request body go template, data is WebhookData, WebhookBodyDefault by default
+	Body         string
+	ContentType  string // application/json by default
+	ItemStringer        // WebhookData.Message of the item
+	// Expect - accepted response statuses, any 2xx by default
+	Expect []int
+	// ExpectBody - optional, response body must contain it
+	ExpectBody string
+	Pause      time.Duration // pause between requests
+	Client     *http.Client
+}
+
+// WebhookData - webhook body template data: item fields and the rendered message
+// (digest text for digest items)
+type WebhookData struct {
+	*Item
+	Message string
+}
+
+type webhookPub struct {
+	WebhookPubParams
+	body *template.Template
+}
+
+// NewWebhookPub creates publisher that sends items as http requests with templated body
+func NewWebhookPub(p WebhookPubParams) (Pub, error) {
+	if strext.IsBlank(p.URL) {
+		return nil, errors.New("webhook pub: url required")
+	}
+	if p.Method == "" {
+		p.Method = http.MethodPost
+	}
+	if p.Body == "" {
+		p.Body = WebhookBodyDefault
+	}
+	if p.ContentType == "" {
+		p.ContentType = "application/json"
+	}
+	if p.ItemStringer == nil {
+		p.ItemStringer = NewItemTemplateStringer("{{.Title}} {{.Link}}")
+	}
+	if p.Client == nil {
+		p.Client = &http.Client{Timeout: time.Minute}
+	}
+	body, err := template.New("webhook-body").Funcs(tplFuncs).Parse(p.Body)
+	if err != nil {
+		return nil, fmt.Errorf("webhook pub: body template: %s", err)
+	}
+	return &webhookPub{WebhookPubParams: p, body: body}, nil
+}
+
+func (pub *webhookPub) Info() *PubInfo {
+	return &pub.PubInfo
+}
+
+func (pub *webhookPub) Publish(ch <-chan *Item) {
+	pub.publishParts(ch, pub.Pause, pub.send)
+}
+
+func (pub *webhookPub) send(it *Item, ps *partSender) error {
+	local := pub.localize(it)
+	return ps.send(it, pub.messages(it, pub.ItemStringer), func(msg string) error {
+		return pub.request(&WebhookData{Item: local, Message: msg})
+	})
+}
+
+func (pub *webhookPub) request(data *WebhookData) error {
+	var buf bytes.Buffer
+	if err := pub.body.Execute(&buf, data); err != nil {
+		return fmt.Errorf("webhook body: %s", err)
+	}
+	req, err := http.NewRequest(pub.Method, pub.URL, &buf)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", pub.ContentType)
+	for k, v := range pub.Headers {
+		req.Header.Set(k, v)
+	}
+	r, err := pub.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer r.Body.Close() // nolint:errcheck
+	resp, _ := io.ReadAll(io.LimitReader(r.Body, 64<<10))
+	if !pub.expected(r.StatusCode) {
+		return &HTTPError{Code: r.StatusCode, Status: r.Status, Body: strings.TrimSpace(string(resp))}
+	}
+	if pub.ExpectBody != "" && !strings.Contains(string(resp), pub.ExpectBody) {
+		return fmt.Errorf("webhook: unexpected response: %q", resp)
+	}
+	return nil
+}
+
+func (pub *webhookPub) expected(st int) bool {
+	if len(pub.Expect) == 0 {
+		return 200 <= st && st < 300
+	}
+	for _, e := range pub.Expect {
+		if st == e {
+			return true
+		}
+	}
+	return false
+}
+
+// HTTPError - unexpected http response status
+type HTTPError struct {
+	Code   int
+	Status string
+	Body   string
+}
+
+func (e *HTTPError) Error() string {
+	if e.Body == "" {
+		return fmt.Sprintf("bad http status: %s", e.Status)
+	}
+	return fmt.Sprintf("bad http status: %s: %s", e.Status, e.Body)
+}
+
+// jsonValue - template func: json representation of the value, e.g. quoted and escaped string
+func jsonValue(v interface{}) (string, error) {
+	b, err := json.Marshal(v)
+	return string(b), err
+}
+
+// jsonEscape - template func: json escaped string without quotes
+func jsonEscape(s string) string {
+	b, _ := json.Marshal(s)
+	return string(b[1 : len(b)-1])
+}
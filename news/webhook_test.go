@@ -0,0 +1,100 @@
+package news
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type webhookReq struct {
+	Method, Auth, ContentType string
+	Body                      []byte
+}
+
+func newWebhookServer(t *testing.T, status int, resp string) (*httptest.Server, *[]webhookReq) {
+	var reqs []webhookReq
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		reqs = append(reqs, webhookReq{r.Method, r.Header.Get("Authorization"), r.Header.Get("Content-Type"), body})
+		w.WriteHeader(status)
+		io.WriteString(w, resp) // nolint:errcheck
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &reqs
+}
+
+func TestWebhookPub(t *testing.T) {
+	assert := assert.New(t)
+	srv, reqs := newWebhookServer(t, http.StatusOK, "ok")
+	pub, err := NewWebhookPub(WebhookPubParams{
+		PubInfo:    PubInfo{Name: "hook"},
+		URL:        srv.URL,
+		Headers:    map[string]string{"Authorization": "Bearer secret"},
+		ExpectBody: "ok",
+	})
+	assert.NoError(err)
+	hook := pub.(*webhookPub)
+	assert.NoError(hook.send(testItem(`"Quoted" <news>`), nil))
+	assert.NoError(hook.send(newDigestItem("line 1\nline 2", []*Item{testItem("x")}), nil))
+	if assert.Len(*reqs, 2) {
+		r := (*reqs)[0]
+		assert.Equal("POST", r.Method)
+		assert.Equal("Bearer secret", r.Auth)
+		assert.Equal("application/json", r.ContentType)
+		var body map[string]string
+		assert.NoError(json.Unmarshal(r.Body, &body))
+		assert.Equal(`"Quoted" <news> http://x/"Quoted" <news>`, body["text"])
+		assert.NoError(json.Unmarshal((*reqs)[1].Body, &body))
+		assert.Equal("line 1\nline 2", body["text"])
+	}
+}
+
+func TestWebhookPubBodyTemplate(t *testing.T) {
+	assert := assert.New(t)
+	srv, reqs := newWebhookServer(t, http.StatusNoContent, "")
+	pub, err := NewWebhookPub(WebhookPubParams{
+		PubInfo: PubInfo{Name: "hook"},
+		Method:  "PUT",
+		URL:     srv.URL,
+		Body:    `{"content": "**{{jsonesc .Title}}** {{jsonesc .Link}}", "tags": {{json .Categories}}}`,
+		Expect:  []int{http.StatusNoContent},
+	})
+	assert.NoError(err)
+	it := testItem("Tab\there")
+	it.Categories = []string{"a", "b"}
+	assert.NoError(pub.(*webhookPub).send(it, nil))
+	if assert.Len(*reqs, 1) {
+		assert.Equal("PUT", (*reqs)[0].Method)
+		var body struct {
+			Content string
+			Tags    []string
+		}
+		assert.NoError(json.Unmarshal((*reqs)[0].Body, &body))
+		assert.Equal("**Tab\there** http://x/Tab\there", body.Content)
+		assert.Equal([]string{"a", "b"}, body.Tags)
+	}
+}
+
+func TestWebhookPubResponseCheck(t *testing.T) {
+	assert := assert.New(t)
+	srv, _ := newWebhookServer(t, http.StatusOK, `{"ok":false}`)
+	pub, _ := NewWebhookPub(WebhookPubParams{PubInfo: PubInfo{Name: "hook"}, URL: srv.URL, ExpectBody: `"ok":true`})
+	assert.Error(pub.(*webhookPub).send(testItem("news"), nil))
+
+	srv, _ = newWebhookServer(t, http.StatusServiceUnavailable, "down")
+	pub, _ = NewWebhookPub(WebhookPubParams{PubInfo: PubInfo{Name: "hook"}, URL: srv.URL})
+	err := pub.(*webhookPub).send(testItem("news"), nil)
+	if assert.Error(err) {
+		assert.Equal(http.StatusServiceUnavailable, err.(*HTTPError).Code)
+		assert.Contains(err.Error(), "down")
+	}
+
+	_, err = NewWebhookPub(WebhookPubParams{URL: srv.URL, Body: "{{.Nope"})
+	assert.Error(err)
+	_, err = NewWebhookPub(WebhookPubParams{})
+	assert.Error(err)
+}
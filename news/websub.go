@@ -0,0 +1,354 @@
+package news
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1" // nolint:gosec
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dlepex/newsmaker/strext"
+	"golang.org/x/net/html/charset"
+)
+
+// WebSubParams - WebSub (PubSubHubbub) subscriber params.
+type WebSubParams struct {
+	// CallbackURL - public url of the callback server (Addr), subscription id is appended to it.
+	CallbackURL string
+	Addr        string        // callback server listen address
+	Lease       time.Duration // requested lease, 24h by default
+	// RetryPause - after failed subscription (or denial) the hub isn't bothered for this duration, feed is polled.
+	RetryPause time.Duration
+	Client     *http.Client
+}
+
+// WebSub is the subscriber: it subscribes feeds to their hubs, verifies intents,
+// accepts content distribution and renews leases.
+// Feed sources poll the link only while its subscription is not active.
+type WebSub struct {
+	WebSubParams
+	mu   sync.Mutex
+	subs map[string]*webSubscription // by link
+	ids  map[string]*webSubscription // by callback id
+}
+
+type webSubState int
+
+const (
+	webSubPending webSubState = iota
+	webSubActive
+	webSubFailed
+)
+
+type webSubscription struct {
+	id, link, topic, hub, secret string
+	onContent                    func([]byte)
+
+	state   webSubState
+	since   time.Time // state change time
+	expires time.Time
+	renew   *time.Timer
+}
+
+// NewWebSub creates WebSub subscriber
+func NewWebSub(p WebSubParams) (*WebSub, error) {
+	if strext.IsBlank(p.CallbackURL) {
+		return nil, errors.New("websub: callback url required")
+	}
+	if _, err := url.Parse(p.CallbackURL); err != nil {
+		return nil, fmt.Errorf("websub: bad callback url: %s", err)
+	}
+	if !strings.HasSuffix(p.CallbackURL, "/") {
+		p.CallbackURL += "/"
+	}
+	if p.Lease == 0 {
+		p.Lease = 24 * time.Hour
+	}
+	if p.RetryPause == 0 {
+		p.RetryPause = time.Hour
+	}
+	if p.Client == nil {
+		p.Client = http.DefaultClient
+	}
+	return &WebSub{
+		WebSubParams: p,
+		subs:         make(map[string]*webSubscription),
+		ids:          make(map[string]*webSubscription),
+	}, nil
+}
+
+// Active - true if the link has active (verified and not expired) subscription, i.e. polling is not needed.
+func (ws *WebSub) Active(link string) bool {
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+	s := ws.subs[link]
+	return s != nil && s.state == webSubActive && time.Now().Before(s.expires)
+}
+
+// Subscribe - subscribes link (whose self url is topic) to the hub, if it's not subscribed yet.
+// onContent receives the distributed content (feed document).
+func (ws *WebSub) Subscribe(link, topic, hub string, onContent func([]byte)) {
+	ws.mu.Lock()
+	s := ws.subs[link]
+	if s != nil && s.hub == hub && s.topic == topic {
+		switch s.state {
+		case webSubPending:
+			if time.Since(s.since) < ws.RetryPause {
+				ws.mu.Unlock()
+				return
+			}
+		case webSubActive:
+			if time.Now().Before(s.expires) {
+				ws.mu.Unlock()
+				return
+			}
+		case webSubFailed:
+			if time.Since(s.since) < ws.RetryPause {
+				ws.mu.Unlock()
+				return
+			}
+		}
+	}
+	if s != nil {
+		ws.drop(s)
+	}
+	s = &webSubscription{
+		id:        randHex(16),
+		link:      link,
+		topic:     topic,
+		hub:       hub,
+		secret:    randHex(20),
+		onContent: onContent,
+		state:     webSubPending,
+		since:     time.Now(),
+	}
+	ws.subs[link] = s
+	ws.ids[s.id] = s
+	ws.mu.Unlock()
+	go ws.request(s)
+}
+
+// drop - must be called under lock
+func (ws *WebSub) drop(s *webSubscription) {
+	if s.renew != nil {
+		s.renew.Stop()
+	}
+	delete(ws.ids, s.id)
+	if ws.subs[s.link] == s {
+		delete(ws.subs, s.link)
+	}
+}
+
+func (ws *WebSub) setState(s *webSubscription, st webSubState) {
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+	s.state = st
+	s.since = time.Now()
+}
+
+// request - sends subscription request to the hub
+func (ws *WebSub) request(s *webSubscription) {
+	form := url.Values{
+		"hub.mode":          {"subscribe"},
+		"hub.topic":         {s.topic},
+		"hub.callback":      {ws.CallbackURL + s.id},
+		"hub.lease_seconds": {strconv.Itoa(int(ws.Lease / time.Second))},
+		"hub.secret":        {s.secret},
+	}
+	r, err := ws.Client.PostForm(s.hub, form)
+	if err == nil {
+		r.Body.Close() // nolint:errcheck
+		if !(200 <= r.StatusCode && r.StatusCode < 300) {
+			err = fmt.Errorf("bad http status: %v (%s)", r.StatusCode, r.Status)
+		}
+	}
+	if err != nil {
+		slog.Errorw("websub_subscribe_error", "err", err, "hub", s.hub, "topic", s.topic)
+		ws.setState(s, webSubFailed)
+		return
+	}
+	slog.Infow("websub_subscribe", "hub", s.hub, "topic", s.topic, "id", s.id)
+}
+
+// verified - hub confirmed the subscription intent.
+func (ws *WebSub) verified(s *webSubscription, lease time.Duration) {
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+	s.state = webSubActive
+	s.since = time.Now()
+	s.expires = s.since.Add(lease)
+	if s.renew != nil {
+		s.renew.Stop()
+	}
+	// renew before expiration, resubscription is done with the same callback id
+	s.renew = time.AfterFunc(lease*9/10, func() {
+		go ws.request(s)
+	})
+	slog.Infow("websub_active", "hub", s.hub, "topic", s.topic, "lease", lease)
+}
+
+func (ws *WebSub) byID(id string) *webSubscription {
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+	return ws.ids[id]
+}
+
+// Handler - callback server handler
+func (ws *WebSub) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.URL.Path[strings.LastIndexByte(r.URL.Path, '/')+1:]
+		s := ws.byID(id)
+		if s == nil {
+			http.NotFound(w, r)
+			return
+		}
+		switch r.Method {
+		case http.MethodGet:
+			ws.onVerify(s, w, r)
+		case http.MethodPost:
+			ws.onContent(s, w, r)
+		default:
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+}
+
+func (ws *WebSub) onVerify(s *webSubscription, w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	if q.Get("hub.topic") != s.topic {
+		http.NotFound(w, r)
+		return
+	}
+	switch q.Get("hub.mode") {
+	case "subscribe":
+		lease := ws.Lease
+		if secs, err := strconv.Atoi(q.Get("hub.lease_seconds")); err == nil && secs > 0 {
+			lease = time.Duration(secs) * time.Second
+		}
+		ws.verified(s, lease)
+		w.Write([]byte(q.Get("hub.challenge"))) // nolint:errcheck
+	case "denied":
+		slog.Errorw("websub_denied", "hub", s.hub, "topic", s.topic, "reason", q.Get("hub.reason"))
+		ws.setState(s, webSubFailed)
+		w.WriteHeader(http.StatusOK)
+	default:
+		// we never unsubscribe
+		http.NotFound(w, r)
+	}
+}
+
+func (ws *WebSub) onContent(s *webSubscription, w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(io.LimitReader(r.Body, 8<<20))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	// per spec, the response is 2xx even if signature is invalid, but the content is ignored
+	w.WriteHeader(http.StatusAccepted)
+	if !checkHubSignature(r.Header.Get("X-Hub-Signature"), s.secret, body) {
+		slog.Errorw("websub_bad_signature", "hub", s.hub, "topic", s.topic)
+		return
+	}
+	slog.Debugw("websub_content", "topic", s.topic, "size", len(body))
+	s.onContent(body)
+}
+
+// Run - runs callback server until quit is closed
+func (ws *WebSub) Run(quit <-chan struct{}) error {
+	srv := &http.Server{Addr: ws.Addr, Handler: ws.Handler()}
+	errc := make(chan error, 1)
+	go func() {
+		errc <- srv.ListenAndServe()
+	}()
+	slog.Infow("websub_listen", "addr", ws.Addr, "callback", ws.CallbackURL)
+	select {
+	case err := <-errc:
+		return err
+	case <-quit:
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		return srv.Shutdown(ctx)
+	}
+}
+
+// checkHubSignature verifies X-Hub-Signature header: "method=hexsignature"
+func checkHubSignature(header, secret string, body []byte) bool {
+	i := strings.IndexByte(header, '=')
+	if i < 0 {
+		return false
+	}
+	var h func() hash.Hash
+	switch header[:i] {
+	case "sha1":
+		h = sha1.New
+	case "sha256":
+		h = sha256.New
+	case "sha384":
+		h = sha512.New384
+	case "sha512":
+		h = sha512.New
+	default:
+		return false
+	}
+	sig, err := hex.DecodeString(header[i+1:])
+	if err != nil {
+		return false
+	}
+	mac := hmac.New(h, []byte(secret))
+	mac.Write(body) // nolint:errcheck
+	return hmac.Equal(sig, mac.Sum(nil))
+}
+
+// findFeedHub scans feed xml for <link rel="hub"> and <link rel="self"> (both atom and rss with atom:link)
+func findFeedHub(data []byte) (hub, self string) {
+	d := xml.NewDecoder(bytes.NewReader(data))
+	d.Strict = false
+	d.CharsetReader = charset.NewReaderLabel
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			return
+		}
+		el, ok := tok.(xml.StartElement)
+		if !ok || el.Name.Local != "link" {
+			continue
+		}
+		var rel, href string
+		for _, a := range el.Attr {
+			switch a.Name.Local {
+			case "rel":
+				rel = a.Value
+			case "href":
+				href = a.Value
+			}
+		}
+		switch {
+		case rel == "hub" && hub == "":
+			hub = href
+		case rel == "self" && self == "":
+			self = href
+		}
+	}
+}
+
+func randHex(n int) string {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b)
+}
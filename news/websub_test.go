@@ -0,0 +1,144 @@
+package news
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const websubFeed = `<?xml version="1.0"?>
+<rss version="2.0" xmlns:atom="http://www.w3.org/2005/Atom"><channel>
+<title>t</title>
+<atom:link rel="hub" href="%s"/>
+<atom:link rel="self" href="http://example.org/feed"/>
+<item><title>%s</title><link>http://example.org/1</link></item>
+</channel></rss>`
+
+func TestFindFeedHub(t *testing.T) {
+	hub, self := findFeedHub([]byte(fmt.Sprintf(websubFeed, "http://hub", "x")))
+	assert.Equal(t, "http://hub", hub)
+	assert.Equal(t, "http://example.org/feed", self)
+
+	// non utf-8 feed
+	hub, _ = findFeedHub([]byte("<?xml version=\"1.0\" encoding=\"windows-1251\"?>\n" +
+		"<feed xmlns=\"http://www.w3.org/2005/Atom\"><title>\xcd\xee\xe2\xee\xf1\xf2\xe8</title><link rel=\"hub\" href=\"http://hub\"/></feed>"))
+	assert.Equal(t, "http://hub", hub)
+
+	hub, self = findFeedHub([]byte(`<feed xmlns="http://www.w3.org/2005/Atom"><link rel="alternate" href="http://a"/></feed>`))
+	assert.Empty(t, hub)
+	assert.Empty(t, self)
+}
+
+func TestCheckHubSignature(t *testing.T) {
+	mac := hmac.New(sha256.New, []byte("secret"))
+	mac.Write([]byte("body"))
+	sig := hex.EncodeToString(mac.Sum(nil))
+	assert.True(t, checkHubSignature("sha256="+sig, "secret", []byte("body")))
+	assert.False(t, checkHubSignature("sha256="+sig, "other", []byte("body")))
+	assert.False(t, checkHubSignature("md5="+sig, "secret", []byte("body")))
+	assert.False(t, checkHubSignature("", "secret", []byte("body")))
+}
+
+// standInHub verifies intent and then distributes content with the subscriber secret
+func standInHub(t *testing.T, content func() string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.NoError(t, r.ParseForm())
+		assert.Equal(t, "subscribe", r.Form.Get("hub.mode"))
+		callback, topic, secret := r.Form.Get("hub.callback"), r.Form.Get("hub.topic"), r.Form.Get("hub.secret")
+		w.WriteHeader(http.StatusAccepted)
+		go func() {
+			q := url.Values{"hub.mode": {"subscribe"}, "hub.topic": {topic}, "hub.challenge": {"ch4llenge"}, "hub.lease_seconds": {"3600"}}
+			resp, err := http.Get(callback + "?" + q.Encode())
+			if !assert.NoError(t, err) {
+				return
+			}
+			body, _ := io.ReadAll(resp.Body)
+			resp.Body.Close()
+			assert.Equal(t, "ch4llenge", string(body))
+
+			data := content()
+			for _, s := range []string{"wrong", secret} {
+				mac := hmac.New(sha256.New, []byte(s))
+				mac.Write([]byte(data))
+				req, _ := http.NewRequest(http.MethodPost, callback, strings.NewReader(data))
+				req.Header.Set("X-Hub-Signature", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+				if resp, err := http.DefaultClient.Do(req); assert.NoError(t, err) {
+					resp.Body.Close()
+				}
+			}
+		}()
+	}))
+}
+
+func TestWebSub(t *testing.T) {
+	var hub *httptest.Server
+	hub = standInHub(t, func() string { return fmt.Sprintf(websubFeed, hub.URL, "pushed") })
+	defer hub.Close()
+
+	var polls int32
+	feed := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&polls, 1)
+		fmt.Fprintf(w, websubFeed, hub.URL, "polled")
+	}))
+	defer feed.Close()
+
+	ws, err := NewWebSub(WebSubParams{CallbackURL: "http://placeholder/"})
+	assert.NoError(t, err)
+	callback := httptest.NewServer(ws.Handler())
+	defer callback.Close()
+	ws.CallbackURL = callback.URL + "/"
+
+	src, err := NewFeedSrc(FeedSrcParams{
+		SourceInfo: SourceInfo{Name: "websub"},
+		Links:      []string{feed.URL},
+		WebSub:     ws,
+	})
+	assert.NoError(t, err)
+
+	// pushed content goes to the pipeline sink, not to the one of Receive (e.g. adaptive counting wrapper)
+	items, pushed := make(chan *Item, 10), make(chan *Item, 10)
+	src.(sinkHolder).setSink(func(it *Item) { pushed <- it })
+	sink := func(it *Item) { items <- it }
+	assert.NoError(t, src.Receive(sink))
+	assert.Equal(t, "polled", (<-items).Title)
+
+	select {
+	case it := <-pushed:
+		assert.Equal(t, "pushed", it.Title)
+	case <-time.After(5 * time.Second):
+		t.Fatal("no content distributed")
+	}
+	assert.True(t, ws.Active(feed.URL))
+	assert.NoError(t, src.Receive(sink))
+	assert.EqualValues(t, 1, atomic.LoadInt32(&polls), "active subscription: no polling")
+	assert.Len(t, pushed, 0, "content with bad signature is ignored")
+}
+
+func TestWebSubHubUnavailable(t *testing.T) {
+	var polls int32
+	feed := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&polls, 1)
+		fmt.Fprintf(w, websubFeed, "http://127.0.0.1:1/hub", "polled")
+	}))
+	defer feed.Close()
+	ws, err := NewWebSub(WebSubParams{CallbackURL: "http://127.0.0.1:1/"})
+	assert.NoError(t, err)
+	src, err := NewFeedSrc(FeedSrcParams{SourceInfo: SourceInfo{Name: "nohub"}, Links: []string{feed.URL}, WebSub: ws})
+	assert.NoError(t, err)
+	for i := 0; i < 3; i++ {
+		assert.NoError(t, src.Receive(func(*Item) {}))
+	}
+	assert.EqualValues(t, 3, atomic.LoadInt32(&polls))
+	assert.False(t, ws.Active(feed.URL))
+}
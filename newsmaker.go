@@ -5,6 +5,7 @@ import (
 	"os"
 	"os/signal"
 	"time"
+	_ "time/tzdata" // time zones are available in containers without tzdata
 
 	"flag"
 
@@ -20,13 +21,49 @@ func main() {
 	log, _ := zap.NewDevelopment()
 	news.SetLogger(log)
 	slog := log.Sugar()
-	cfgpath := flag.Arg(0)
-	slog.Infow("starting newsmaker", "cfgpath", cfgpath)
+	cmd, cfgpath := "", flag.Arg(0)
+	if flag.NArg() > 1 {
+		cmd, cfgpath = flag.Arg(0), flag.Arg(1)
+	}
 	var conf config
 	_, err := toml.DecodeFile(cfgpath, &conf)
 	if err != nil {
 		slog.Fatalf("config parse err: %s", err)
 	}
+	switch cmd {
+	case "":
+	case "export-opml":
+		if err := conf.exportOPML(os.Stdout); err != nil {
+			slog.Fatalf("export-opml: %s", err)
+		}
+		return
+	case "dead-letter":
+		if err := conf.listDeadLetter(os.Stdout); err != nil {
+			slog.Fatalf("dead-letter: %s", err)
+		}
+		return
+	case "resend":
+		n, err := conf.resend(flag.Args()[2:])
+		if err != nil {
+			slog.Fatalf("resend: %s", err)
+		}
+		slog.Infow("resent", "count", n)
+		return
+	case "fetch":
+		if conf.Admin == nil {
+			slog.Fatalf("fetch: [admin] isn't configured")
+		}
+		for _, src := range flag.Args()[2:] {
+			if err := conf.Admin.params().Fetch(src); err != nil {
+				slog.Fatalf("fetch: %s", err)
+			}
+			slog.Infow("fetched", "src", src)
+		}
+		return
+	default:
+		slog.Fatalf("unknown command: %s", cmd)
+	}
+	slog.Infow("starting newsmaker", "cfgpath", cfgpath)
 
 	pl, ers := conf.newPipeline()
 
@@ -38,18 +75,41 @@ func main() {
 	if err != nil {
 		slog.Fatalf("pipeline start error: %s", err)
 	}
-	setupSignalHandlers(log)
+	if ws := conf.websub; ws != nil {
+		go func() {
+			if err := ws.Run(nil); err != nil {
+				slog.Fatalf("websub callback server error: %s", err)
+			}
+		}()
+	}
+	if conf.Admin != nil {
+		admin, err := news.NewAdmin(pl, conf.Admin.params())
+		if err != nil {
+			slog.Fatalf("admin: %s", err)
+		}
+		go func() {
+			if err := admin.Run(nil); err != nil {
+				slog.Fatalf("admin server error: %s", err)
+			}
+		}()
+	}
+	setupSignalHandlers(log, pl)
 
 	pl.Wait()
+	log.Sync() //nolint:errcheck
 }
 
-func setupSignalHandlers(l *zap.Logger) {
+// setupSignalHandlers - the first interrupt stops the pipeline: queued items and digests are published
+// before exit, the second one exits immediately.
+func setupSignalHandlers(l *zap.Logger, pl *news.Pipeline) {
 	c := make(chan os.Signal, 1)
 	signal.Notify(c, os.Interrupt)
 	go func() {
-		for range c {
-			l.Sync() //nolint:errcheck
-			os.Exit(0)
-		}
+		<-c
+		l.Sugar().Infow("stopping newsmaker")
+		go pl.Stop()
+		<-c
+		l.Sync() //nolint:errcheck
+		os.Exit(1)
 	}()
 }